@@ -0,0 +1,129 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/spf13/cast"
+)
+
+// incrValue increase the value by delta
+func incrValue(v interface{}, delta int64) (int64, error) {
+	if v == nil {
+		return delta, nil
+	}
+	value, err := cast.ToInt64E(v)
+	if err != nil {
+		return 0, err
+	}
+	return value + delta, nil
+}
+
+// pushValue prepend the value to the list, the list will be trimmed to max length
+func pushValue(v interface{}, value interface{}, maxLen int) []interface{} {
+	values := append([]interface{}{value}, cast.ToSlice(v)...)
+	if maxLen > 0 && len(values) > maxLen {
+		values = values[:maxLen]
+	}
+	return values
+}
+
+// addToSetValue add the value to the set if it doesn't exist,
+// the values are compared by their json
+func addToSetValue(v interface{}, value interface{}) []interface{} {
+	values := cast.ToSlice(v)
+	buf, _ := json.Marshal(value)
+	for _, item := range values {
+		data, _ := json.Marshal(item)
+		if bytes.Equal(buf, data) {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// getAtomicStore get the atomic store if the key can be modified in store,
+// the session which isn't stored or the key which has been changed
+// should be modified in memory
+func (sess *Session) getAtomicStore(key string) (AtomicStore, bool) {
+	if !sess.stored || sess.prevCookieValue != "" || sess.dirty[key] {
+		return nil, false
+	}
+	if sess.opts.Versioned {
+		return nil, false
+	}
+	store, ok := sess.opts.Store.(AtomicStore)
+	return store, ok
+}
+
+// Incr increase the value of key by delta and return the new value. If the store is
+// an AtomicStore, it will be executed in store atomically, otherwise the session
+// data will be modified and it should be committed.
+func (sess *Session) Incr(key string, delta int64) (value int64, err error) {
+	if !sess.fetched {
+		err = ErrNotFetched
+		return
+	}
+	if store, ok := sess.getAtomicStore(key); ok {
+		value, err = store.Incr(sess.cookieValue, key, delta, sess.GetMaxAge())
+		if err == nil {
+			sess.data[key] = value
+			return
+		}
+		if err != ErrNotExists {
+			return
+		}
+	}
+	value, err = incrValue(sess.data[key], delta)
+	if err != nil {
+		return
+	}
+	err = sess.Set(key, value)
+	return
+}
+
+// Push prepend the value to the list of key, the list will be trimmed to max length
+// if it's greater than 0. If the store is an AtomicStore, it will be executed in
+// store atomically, otherwise the session data will be modified and it should be committed.
+func (sess *Session) Push(key string, value interface{}, maxLen int) (values []interface{}, err error) {
+	if !sess.fetched {
+		err = ErrNotFetched
+		return
+	}
+	if store, ok := sess.getAtomicStore(key); ok {
+		values, err = store.Push(sess.cookieValue, key, value, maxLen, sess.GetMaxAge())
+		if err == nil {
+			sess.data[key] = values
+			return
+		}
+		if err != ErrNotExists {
+			return
+		}
+	}
+	values = pushValue(sess.data[key], value, maxLen)
+	err = sess.Set(key, values)
+	return
+}
+
+// AddToSet add the value to the set of key if it doesn't exist. If the store is an
+// AtomicStore, it will be executed in store atomically, otherwise the session data
+// will be modified and it should be committed.
+func (sess *Session) AddToSet(key string, value interface{}) (values []interface{}, err error) {
+	if !sess.fetched {
+		err = ErrNotFetched
+		return
+	}
+	if store, ok := sess.getAtomicStore(key); ok {
+		values, err = store.AddToSet(sess.cookieValue, key, value, sess.GetMaxAge())
+		if err == nil {
+			sess.data[key] = values
+			return
+		}
+		if err != ErrNotExists {
+			return
+		}
+	}
+	values = addToSetValue(sess.data[key], value)
+	err = sess.Set(key, values)
+	return
+}
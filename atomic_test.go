@@ -0,0 +1,158 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/go-redis/redis"
+)
+
+func TestAtomic(t *testing.T) {
+	opts := Options{
+		MaxAge: 60,
+	}
+	ms, _ := NewMemoryStore(10)
+	rhs := NewRedisHashStore(nil, &redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	t.Run("value", func(t *testing.T) {
+		v, err := incrValue(nil, 2)
+		if err != nil || v != 2 {
+			t.Fatalf("incr nil value fail, %v", err)
+		}
+		v, err = incrValue("3", 2)
+		if err != nil || v != 5 {
+			t.Fatalf("incr string value fail, %v", err)
+		}
+		_, err = incrValue("a", 2)
+		if err == nil {
+			t.Fatalf("incr invalid value should return error")
+		}
+		values := pushValue([]interface{}{1, 2}, 3, 2)
+		if !reflect.DeepEqual(values, []interface{}{3, 1}) {
+			t.Fatalf("push value fail")
+		}
+		values = addToSetValue([]interface{}{float64(1), "a"}, 1)
+		if len(values) != 2 {
+			t.Fatalf("the exists value should not be added to set")
+		}
+		values = addToSetValue(values, "1")
+		if len(values) != 3 {
+			t.Fatalf("add value to set fail")
+		}
+	})
+
+	t.Run("not fetched", func(t *testing.T) {
+		sess := newHeaderSession(ms, "", opts)
+		_, err := sess.Incr("count", 1)
+		if err != ErrNotFetched {
+			t.Fatalf("should return not fetched error")
+		}
+		_, err = sess.Push("viewed", 1, 10)
+		if err != ErrNotFetched {
+			t.Fatalf("should return not fetched error")
+		}
+		_, err = sess.AddToSet("roles", "admin")
+		if err != ErrNotFetched {
+			t.Fatalf("should return not fetched error")
+		}
+	})
+
+	t.Run("in memory", func(t *testing.T) {
+		sess := newHeaderSession(ms, "", opts)
+		sess.Fetch()
+		sess.Incr("count", 1)
+		count, err := sess.Incr("count", 2)
+		if err != nil || count != 3 {
+			t.Fatalf("incr session data fail, %v", err)
+		}
+		sess.Push("viewed", 1, 2)
+		sess.Push("viewed", 2, 2)
+		viewed, err := sess.Push("viewed", 3, 2)
+		if err != nil || !reflect.DeepEqual(viewed, []interface{}{3, 2}) {
+			t.Fatalf("push session data fail, %v", err)
+		}
+		sess.AddToSet("roles", "admin")
+		roles, err := sess.AddToSet("roles", "admin")
+		if err != nil || len(roles) != 1 {
+			t.Fatalf("add to set of session data fail, %v", err)
+		}
+		changed, _ := sess.Changes()
+		if len(changed) != 4 {
+			t.Fatalf("the session should be modified")
+		}
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+	})
+
+	stores := map[string]Store{
+		"memory":     ms,
+		"redis hash": rhs,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			id := generateID()
+			buf, _ := json.Marshal(M{
+				"count": 1,
+			})
+			store.Set(id, buf, 60)
+
+			wg := sync.WaitGroup{}
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					sess := newHeaderSession(store, id, opts)
+					sess.Fetch()
+					_, err := sess.Incr("count", 2)
+					if err != nil {
+						t.Errorf("incr session data fail, %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			sess := newHeaderSession(store, id, opts)
+			sess.Fetch()
+			if sess.GetInt("count") != 21 {
+				t.Fatalf("incr should be executed in store atomically")
+			}
+			sess.Push("viewed", "a", 2)
+			sess.Push("viewed", "b", 2)
+			viewed, err := sess.Push("viewed", "c", 2)
+			if err != nil || !reflect.DeepEqual(viewed, []interface{}{"c", "b"}) {
+				t.Fatalf("push session data in store fail, %v", err)
+			}
+			sess.AddToSet("roles", "admin")
+			roles, err := sess.AddToSet("roles", "admin")
+			if err != nil || len(roles) != 1 {
+				t.Fatalf("add to set of session data in store fail, %v", err)
+			}
+			changed, _ := sess.Changes()
+			if len(changed) != 0 {
+				t.Fatalf("the atomic operation in store should not modify the session")
+			}
+
+			sess = newHeaderSession(store, id, opts)
+			sess.Fetch()
+			if !reflect.DeepEqual(sess.GetStringSlice("viewed"), []string{"c", "b"}) ||
+				!reflect.DeepEqual(sess.GetStringSlice("roles"), []string{"admin"}) {
+				t.Fatalf("the data should be saved in store")
+			}
+
+			// the session has been destroyed
+			sess = newHeaderSession(store, id, opts)
+			sess.Fetch()
+			store.Destroy(id)
+			count, err := sess.Incr("count", 1)
+			if err != nil || count != 22 {
+				t.Fatalf("incr should fall back to memory if session is not exists, %v", err)
+			}
+		})
+	}
+}
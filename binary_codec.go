@@ -0,0 +1,464 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	binaryNil byte = iota
+	binaryFalse
+	binaryTrue
+	binaryInt
+	binaryInt8
+	binaryInt16
+	binaryInt32
+	binaryInt64
+	binaryUint
+	binaryUint8
+	binaryUint16
+	binaryUint32
+	binaryUint64
+	binaryFloat32
+	binaryFloat64
+	binaryString
+	binaryBytes
+	binaryTime
+	binaryDuration
+	binarySlice
+	binaryMap
+	binaryM
+	binaryStringSlice
+	binaryIntSlice
+	binaryStringMap
+)
+
+var (
+	// ErrInvalidBinaryData invalid binary data error
+	ErrInvalidBinaryData = errors.New("invalid binary data")
+)
+
+type (
+	// BinaryCodec compact binary codec, it preserves the go types of
+	// basic value, []byte, time.Time, time.Duration, slice and map
+	BinaryCodec struct{}
+
+	binaryDecoder struct {
+		data []byte
+	}
+)
+
+// ID the id of binary codec
+func (c *BinaryCodec) ID() byte {
+	return CodecIDBinary
+}
+
+// Marshal marshal the data to binary
+func (c *BinaryCodec) Marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := writeBinary(buf, v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal unmarshal the binary data, v should be *M, *map[string]interface{} or *interface{}
+func (c *BinaryCodec) Unmarshal(data []byte, v interface{}) (err error) {
+	d := &binaryDecoder{
+		data: data,
+	}
+	value, err := d.read()
+	if err != nil {
+		return
+	}
+	if len(d.data) != 0 {
+		return ErrInvalidBinaryData
+	}
+	switch p := v.(type) {
+	case *interface{}:
+		*p = value
+	case *M:
+		m, ok := toM(value)
+		if !ok {
+			return ErrInvalidBinaryData
+		}
+		*p = m
+	case *map[string]interface{}:
+		m, ok := toM(value)
+		if !ok {
+			return ErrInvalidBinaryData
+		}
+		*p = m
+	default:
+		return fmt.Errorf("unsupported unmarshal type %T", v)
+	}
+	return
+}
+
+// toM convert the map value to M
+func toM(value interface{}) (M, bool) {
+	switch m := value.(type) {
+	case M:
+		return m, true
+	case map[string]interface{}:
+		return M(m), true
+	}
+	return nil, false
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	buf.Write(b[:binary.PutUvarint(b, v)])
+}
+
+func writeVarint(buf *bytes.Buffer, v int64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	buf.Write(b[:binary.PutVarint(b, v)])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func writeBinary(buf *bytes.Buffer, v interface{}) (err error) {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteByte(binaryNil)
+	case bool:
+		if value {
+			buf.WriteByte(binaryTrue)
+		} else {
+			buf.WriteByte(binaryFalse)
+		}
+	case int:
+		buf.WriteByte(binaryInt)
+		writeVarint(buf, int64(value))
+	case int8:
+		buf.WriteByte(binaryInt8)
+		writeVarint(buf, int64(value))
+	case int16:
+		buf.WriteByte(binaryInt16)
+		writeVarint(buf, int64(value))
+	case int32:
+		buf.WriteByte(binaryInt32)
+		writeVarint(buf, int64(value))
+	case int64:
+		buf.WriteByte(binaryInt64)
+		writeVarint(buf, value)
+	case uint:
+		buf.WriteByte(binaryUint)
+		writeUvarint(buf, uint64(value))
+	case uint8:
+		buf.WriteByte(binaryUint8)
+		writeUvarint(buf, uint64(value))
+	case uint16:
+		buf.WriteByte(binaryUint16)
+		writeUvarint(buf, uint64(value))
+	case uint32:
+		buf.WriteByte(binaryUint32)
+		writeUvarint(buf, uint64(value))
+	case uint64:
+		buf.WriteByte(binaryUint64)
+		writeUvarint(buf, value)
+	case float32:
+		buf.WriteByte(binaryFloat32)
+		writeUvarint(buf, uint64(math.Float32bits(value)))
+	case float64:
+		buf.WriteByte(binaryFloat64)
+		writeUvarint(buf, math.Float64bits(value))
+	case string:
+		buf.WriteByte(binaryString)
+		writeString(buf, value)
+	case []byte:
+		buf.WriteByte(binaryBytes)
+		writeUvarint(buf, uint64(len(value)))
+		buf.Write(value)
+	case time.Time:
+		b, e := value.MarshalBinary()
+		if e != nil {
+			return e
+		}
+		buf.WriteByte(binaryTime)
+		writeUvarint(buf, uint64(len(b)))
+		buf.Write(b)
+	case time.Duration:
+		buf.WriteByte(binaryDuration)
+		writeVarint(buf, int64(value))
+	case []interface{}:
+		buf.WriteByte(binarySlice)
+		writeUvarint(buf, uint64(len(value)))
+		for _, item := range value {
+			err = writeBinary(buf, item)
+			if err != nil {
+				return
+			}
+		}
+	case []string:
+		buf.WriteByte(binaryStringSlice)
+		writeUvarint(buf, uint64(len(value)))
+		for _, item := range value {
+			writeString(buf, item)
+		}
+	case []int:
+		buf.WriteByte(binaryIntSlice)
+		writeUvarint(buf, uint64(len(value)))
+		for _, item := range value {
+			writeVarint(buf, int64(item))
+		}
+	case map[string]string:
+		buf.WriteByte(binaryStringMap)
+		writeUvarint(buf, uint64(len(value)))
+		for k, item := range value {
+			writeString(buf, k)
+			writeString(buf, item)
+		}
+	case M:
+		buf.WriteByte(binaryM)
+		err = writeBinaryMap(buf, value)
+	case map[string]interface{}:
+		buf.WriteByte(binaryMap)
+		err = writeBinaryMap(buf, value)
+	default:
+		err = fmt.Errorf("unsupported binary type %T", v)
+	}
+	return
+}
+
+func writeBinaryMap(buf *bytes.Buffer, m map[string]interface{}) (err error) {
+	writeUvarint(buf, uint64(len(m)))
+	for k, item := range m {
+		writeString(buf, k)
+		err = writeBinary(buf, item)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (d *binaryDecoder) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, ErrInvalidBinaryData
+	}
+	d.data = d.data[n:]
+	return v, nil
+}
+
+func (d *binaryDecoder) readVarint() (int64, error) {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		return 0, ErrInvalidBinaryData
+	}
+	d.data = d.data[n:]
+	return v, nil
+}
+
+func (d *binaryDecoder) readBytes() ([]byte, error) {
+	size, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(d.data)) < size {
+		return nil, ErrInvalidBinaryData
+	}
+	b := d.data[:size]
+	d.data = d.data[size:]
+	return b, nil
+}
+
+func (d *binaryDecoder) readString() (string, error) {
+	b, err := d.readBytes()
+	return string(b), err
+}
+
+// readLength read the length of slice or map, the length can't be
+// bigger than the size of rest data
+func (d *binaryDecoder) readLength() (int, error) {
+	size, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if size > uint64(len(d.data)) {
+		return 0, ErrInvalidBinaryData
+	}
+	return int(size), nil
+}
+
+func (d *binaryDecoder) readMap() (m map[string]interface{}, err error) {
+	size, err := d.readLength()
+	if err != nil {
+		return
+	}
+	m = make(map[string]interface{}, size)
+	for i := 0; i < size; i++ {
+		k, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		m[k], err = d.read()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func (d *binaryDecoder) read() (value interface{}, err error) {
+	if len(d.data) == 0 {
+		return nil, ErrInvalidBinaryData
+	}
+	t := d.data[0]
+	d.data = d.data[1:]
+	var i int64
+	var u uint64
+	switch t {
+	case binaryNil:
+		return nil, nil
+	case binaryFalse:
+		return false, nil
+	case binaryTrue:
+		return true, nil
+	case binaryInt, binaryInt8, binaryInt16, binaryInt32, binaryInt64, binaryDuration:
+		i, err = d.readVarint()
+		if err != nil {
+			return
+		}
+		switch t {
+		case binaryInt:
+			value = int(i)
+		case binaryInt8:
+			value = int8(i)
+		case binaryInt16:
+			value = int16(i)
+		case binaryInt32:
+			value = int32(i)
+		case binaryInt64:
+			value = i
+		default:
+			value = time.Duration(i)
+		}
+	case binaryUint, binaryUint8, binaryUint16, binaryUint32, binaryUint64, binaryFloat32, binaryFloat64:
+		u, err = d.readUvarint()
+		if err != nil {
+			return
+		}
+		switch t {
+		case binaryUint:
+			value = uint(u)
+		case binaryUint8:
+			value = uint8(u)
+		case binaryUint16:
+			value = uint16(u)
+		case binaryUint32:
+			value = uint32(u)
+		case binaryUint64:
+			value = u
+		case binaryFloat32:
+			value = math.Float32frombits(uint32(u))
+		default:
+			value = math.Float64frombits(u)
+		}
+	case binaryString:
+		return d.readString()
+	case binaryBytes:
+		var b []byte
+		b, err = d.readBytes()
+		if err != nil {
+			return
+		}
+		value = append([]byte{}, b...)
+	case binaryTime:
+		var b []byte
+		b, err = d.readBytes()
+		if err != nil {
+			return
+		}
+		tm := time.Time{}
+		err = tm.UnmarshalBinary(b)
+		value = tm
+	case binarySlice:
+		var size int
+		size, err = d.readLength()
+		if err != nil {
+			return
+		}
+		items := make([]interface{}, size)
+		for index := range items {
+			items[index], err = d.read()
+			if err != nil {
+				return
+			}
+		}
+		value = items
+	case binaryStringSlice:
+		var size int
+		size, err = d.readLength()
+		if err != nil {
+			return
+		}
+		items := make([]string, size)
+		for index := range items {
+			items[index], err = d.readString()
+			if err != nil {
+				return
+			}
+		}
+		value = items
+	case binaryIntSlice:
+		var size int
+		size, err = d.readLength()
+		if err != nil {
+			return
+		}
+		items := make([]int, size)
+		for index := range items {
+			i, err = d.readVarint()
+			if err != nil {
+				return
+			}
+			items[index] = int(i)
+		}
+		value = items
+	case binaryStringMap:
+		var size int
+		size, err = d.readLength()
+		if err != nil {
+			return
+		}
+		m := make(map[string]string, size)
+		for index := 0; index < size; index++ {
+			var k, v string
+			k, err = d.readString()
+			if err != nil {
+				return
+			}
+			v, err = d.readString()
+			if err != nil {
+				return
+			}
+			m[k] = v
+		}
+		value = m
+	case binaryM, binaryMap:
+		var m map[string]interface{}
+		m, err = d.readMap()
+		if err != nil {
+			return
+		}
+		if t == binaryM {
+			value = M(m)
+		} else {
+			value = m
+		}
+	default:
+		err = ErrInvalidBinaryData
+	}
+	return
+}
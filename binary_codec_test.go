@@ -0,0 +1,119 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBinaryCodec(t *testing.T) {
+	codec := &BinaryCodec{}
+
+	t.Run("marshal and unmarshal", func(t *testing.T) {
+		now := time.Now()
+		data := M{
+			"nil":      nil,
+			"bool":     true,
+			"int":      -1,
+			"int8":     int8(-8),
+			"int16":    int16(16),
+			"int32":    int32(32),
+			"int64":    int64(64),
+			"uint":     uint(1),
+			"uint8":    uint8(8),
+			"uint16":   uint16(16),
+			"uint32":   uint32(32),
+			"uint64":   uint64(64),
+			"float32":  float32(1.5),
+			"float64":  10.1,
+			"string":   "tree.xie",
+			"bytes":    []byte("tree.xie"),
+			"time":     now,
+			"duration": time.Second,
+			"slice": []interface{}{
+				1,
+				"a",
+			},
+			"strings": []string{
+				"a",
+				"b",
+			},
+			"ints": []int{
+				1,
+				2,
+			},
+			"stringMap": map[string]string{
+				"a": "1",
+			},
+			"map": map[string]interface{}{
+				"a": false,
+			},
+			"m": M{
+				"b": 1,
+			},
+		}
+		buf, err := codec.Marshal(data)
+		if err != nil {
+			t.Fatalf("marshal data fail, %v", err)
+		}
+		m := make(M)
+		err = codec.Unmarshal(buf, &m)
+		if err != nil {
+			t.Fatalf("unmarshal data fail, %v", err)
+		}
+		if !m["time"].(time.Time).Equal(now) {
+			t.Fatalf("the time should be preserved")
+		}
+		delete(m, "time")
+		delete(data, "time")
+		if !reflect.DeepEqual(m, data) {
+			t.Fatalf("the data should be preserved after unmarshal")
+		}
+
+		var v interface{}
+		err = codec.Unmarshal(buf, &v)
+		if err != nil {
+			t.Fatalf("unmarshal data to interface fail, %v", err)
+		}
+		if _, ok := v.(M); !ok {
+			t.Fatalf("unmarshal data to interface fail")
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		_, err := codec.Marshal(M{
+			"a": struct{}{},
+		})
+		if err == nil {
+			t.Fatalf("unsupported type should return error")
+		}
+		buf, _ := codec.Marshal(M{})
+		var s string
+		err = codec.Unmarshal(buf, &s)
+		if err == nil {
+			t.Fatalf("unsupported unmarshal type should return error")
+		}
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		m := make(M)
+		for _, buf := range [][]byte{
+			nil,
+			{binaryM, 10},
+			{binaryString, 10, 'a'},
+			{binaryInt},
+			{100},
+			{binaryTrue, binaryTrue},
+		} {
+			err := codec.Unmarshal(buf, &m)
+			if err == nil {
+				t.Fatalf("invalid data should return error")
+			}
+		}
+		buf, _ := codec.Marshal("a")
+		err := codec.Unmarshal(buf, &m)
+		if err != ErrInvalidBinaryData {
+			t.Fatalf("unmarshal not map data to M should return error")
+		}
+	})
+}
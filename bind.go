@@ -0,0 +1,185 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	bindTagName = "session"
+)
+
+var (
+	// ErrInvalidBindTarget invalid bind target error
+	ErrInvalidBindTarget = errors.New("the bind target should be a pointer of struct")
+	// ErrInvalidSaveValue invalid save value error
+	ErrInvalidSaveValue = errors.New("the save value should be a struct or a pointer of struct")
+
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// getFieldName get the session key of struct field by `session:"name"` tag,
+// the unexported field and the field with "-" tag will be ignored(return "")
+func getFieldName(field reflect.StructField) (name string, omitempty bool) {
+	if field.PkgPath != "" {
+		return
+	}
+	arr := strings.Split(field.Tag.Get(bindTagName), ",")
+	name = arr[0]
+	if name == "-" {
+		name = ""
+		return
+	}
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range arr[1:] {
+		if option == "omitempty" {
+			omitempty = true
+		}
+	}
+	return
+}
+
+// setField set the value to field, the value will be converted to the type of field
+func setField(field reflect.Value, value interface{}) (err error) {
+	if value == nil {
+		return
+	}
+	rv := reflect.ValueOf(value)
+	fieldType := field.Type()
+	if rv.Type().AssignableTo(fieldType) {
+		field.Set(rv)
+		return
+	}
+	switch {
+	case fieldType == timeType:
+		var t time.Time
+		t, err = cast.ToTimeE(value)
+		if err == nil {
+			field.Set(reflect.ValueOf(t))
+		}
+		return
+	case fieldType == durationType:
+		var d time.Duration
+		d, err = cast.ToDurationE(value)
+		if err == nil {
+			field.SetInt(int64(d))
+		}
+		return
+	}
+	switch fieldType.Kind() {
+	case reflect.String:
+		var s string
+		s, err = cast.ToStringE(value)
+		if err == nil {
+			field.SetString(s)
+		}
+	case reflect.Bool:
+		var b bool
+		b, err = cast.ToBoolE(value)
+		if err == nil {
+			field.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		i, err = cast.ToInt64E(value)
+		if err == nil {
+			field.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		u, err = cast.ToUint64E(value)
+		if err == nil {
+			field.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = cast.ToFloat64E(value)
+		if err == nil {
+			field.SetFloat(f)
+		}
+	default:
+		// convert the complex value(struct, slice, map) by json
+		var buf []byte
+		buf, err = json.Marshal(value)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal(buf, field.Addr().Interface())
+	}
+	return
+}
+
+// Bind bind the session data to the struct, v should be a pointer of struct.
+// The field is mapped by `session:"name"` tag, or the field name if the tag isn't set.
+func (sess *Session) Bind(v interface{}) (err error) {
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidBindTarget
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, _ := getFieldName(rt.Field(i))
+		if name == "" {
+			continue
+		}
+		err = setField(rv.Field(i), sess.data[name])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Save save the struct to session data, v should be a struct or a pointer of struct.
+// The field with omitempty option will be removed from session data if it's zero value.
+func (sess *Session) Save(v interface{}) (err error) {
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ErrInvalidSaveValue
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ErrInvalidSaveValue
+	}
+	rt := rv.Type()
+	m := make(map[string]interface{})
+	for i := 0; i < rt.NumField(); i++ {
+		name, omitempty := getFieldName(rt.Field(i))
+		if name == "" {
+			continue
+		}
+		field := rv.Field(i)
+		if omitempty && isZero(field) {
+			m[name] = nil
+			continue
+		}
+		m[name] = field.Interface()
+	}
+	return sess.SetMap(m)
+}
+
+// isZero check the value is zero value
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
@@ -0,0 +1,126 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+type bindUser struct {
+	Account   string        `session:"account"`
+	Age       int           `session:"age"`
+	Score     uint          `session:"score"`
+	Rate      float32       `session:"rate"`
+	Admin     bool          `session:"admin"`
+	LoginAt   time.Time     `session:"loginAt"`
+	Timeout   time.Duration `session:"timeout"`
+	Roles     []string      `session:"roles"`
+	Profile   bindProfile   `session:"profile"`
+	Nickname  string        `session:"nickname,omitempty"`
+	Ignore    string        `session:"-"`
+	Name      string
+	unexposed string
+}
+
+type bindProfile struct {
+	Locale string `json:"locale"`
+}
+
+func TestBind(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+
+	t.Run("bind", func(t *testing.T) {
+		sess := Mock(M{
+			"fetched": true,
+			"data": M{
+				"account": "tree.xie",
+				"age":     float64(30),
+				"score":   "100",
+				"rate":    1.5,
+				"admin":   true,
+				"loginAt": now,
+				"timeout": "1s",
+				"roles": []interface{}{
+					"admin",
+				},
+				"profile": map[string]interface{}{
+					"locale": "zh",
+				},
+				"Ignore":    "a",
+				"Name":      "vicanso",
+				"unexposed": "a",
+			},
+		})
+		u := bindUser{}
+		err := sess.Bind(&u)
+		if err != nil {
+			t.Fatalf("bind session data fail, %v", err)
+		}
+		if u.Account != "tree.xie" ||
+			u.Age != 30 ||
+			u.Score != 100 ||
+			u.Rate != 1.5 ||
+			!u.Admin ||
+			u.LoginAt.Format(time.RFC3339) != now ||
+			u.Timeout != time.Second ||
+			len(u.Roles) != 1 ||
+			u.Profile.Locale != "zh" ||
+			u.Ignore != "" ||
+			u.Name != "vicanso" ||
+			u.unexposed != "" {
+			t.Fatalf("bind session data fail")
+		}
+
+		err = sess.Bind(u)
+		if err != ErrInvalidBindTarget {
+			t.Fatalf("bind not pointer should return error")
+		}
+		err = Mock(M{}).Bind(&u)
+		if err != ErrNotFetched {
+			t.Fatalf("the session should fetch before bind")
+		}
+	})
+
+	t.Run("bind fail", func(t *testing.T) {
+		sess := Mock(M{
+			"fetched": true,
+			"data": M{
+				"age": "a",
+			},
+		})
+		u := bindUser{}
+		err := sess.Bind(&u)
+		if err == nil {
+			t.Fatalf("bind invalid data should return error")
+		}
+	})
+
+	t.Run("save", func(t *testing.T) {
+		sess := Mock(M{
+			"fetched": true,
+			"data": M{
+				"nickname": "tree",
+			},
+		})
+		err := sess.Save(&bindUser{
+			Account: "tree.xie",
+			Age:     30,
+			Ignore:  "a",
+		})
+		if err != nil {
+			t.Fatalf("save struct fail, %v", err)
+		}
+		data := sess.GetData()
+		if data["account"] != "tree.xie" ||
+			data["age"] != 30 ||
+			data["nickname"] != nil ||
+			data["Ignore"] != nil ||
+			!sess.modified {
+			t.Fatalf("save struct fail")
+		}
+
+		err = sess.Save("a")
+		if err != ErrInvalidSaveValue {
+			t.Fatalf("save not struct should return error")
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vicanso/cookies"
+)
+
+const (
+	defaultChunkSize = 4000
+)
+
+type (
+	// ChunkedReadWriter cookie reader and writer which splits the large cookie
+	// into chunks(name.0, name.1, ...) and reassembles them on read
+	ChunkedReadWriter struct {
+		rw cookies.ReadWriter
+		// the max size of each chunk, default is 4000
+		ChunkSize int
+	}
+)
+
+// getChunkName get the name of chunk cookie
+func getChunkName(name string, index int) string {
+	return name + "." + strconv.Itoa(index)
+}
+
+// Cookie get the cookie which is reassembled from chunks,
+// if there isn't any chunk, the not chunked cookie will be returned
+func (crw *ChunkedReadWriter) Cookie(name string) (*http.Cookie, error) {
+	first, err := crw.rw.Cookie(getChunkName(name, 0))
+	if err != nil {
+		return crw.rw.Cookie(name)
+	}
+	values := []string{
+		first.Value,
+	}
+	for i := 1; ; i++ {
+		c, err := crw.rw.Cookie(getChunkName(name, i))
+		if err != nil {
+			break
+		}
+		values = append(values, c.Value)
+	}
+	cookie := *first
+	cookie.Name = name
+	cookie.Value = strings.Join(values, "")
+	return &cookie, nil
+}
+
+// SetCookie split the cookie into chunks and set them,
+// the stale chunks of request will be removed
+func (crw *ChunkedReadWriter) SetCookie(cookie *http.Cookie) (err error) {
+	size := crw.ChunkSize
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	value := cookie.Value
+	count := 0
+	for {
+		end := size
+		if end > len(value) {
+			end = len(value)
+		}
+		c := *cookie
+		c.Name = getChunkName(cookie.Name, count)
+		c.Value = value[:end]
+		err = crw.rw.SetCookie(&c)
+		if err != nil {
+			return
+		}
+		count++
+		value = value[end:]
+		if len(value) == 0 {
+			break
+		}
+	}
+	// remove the stale chunks
+	for i := count; ; i++ {
+		name := getChunkName(cookie.Name, i)
+		_, e := crw.rw.Cookie(name)
+		if e != nil {
+			break
+		}
+		c := *cookie
+		c.Name = name
+		c.Value = ""
+		c.MaxAge = -1
+		c.Expires = time.Unix(0, 0)
+		err = crw.rw.SetCookie(&c)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// NewChunkedReadWriter create a chunked cookie reader and writer
+func NewChunkedReadWriter(rw cookies.ReadWriter, chunkSize int) *ChunkedReadWriter {
+	return &ChunkedReadWriter{
+		rw:        rw,
+		ChunkSize: chunkSize,
+	}
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vicanso/cookies"
+)
+
+func TestChunkedReadWriter(t *testing.T) {
+	value := strings.Repeat("a", 25)
+
+	t.Run("set and get", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		crw := NewChunkedReadWriter(cookies.NewHTTPReadWriter(r, w), 10)
+		err := crw.SetCookie(&http.Cookie{
+			Name:  defaultCookieName,
+			Value: value,
+		})
+		if err != nil {
+			t.Fatalf("set chunked cookie fail, %v", err)
+		}
+		result := w.Result().Cookies()
+		if len(result) != 3 || result[2].Name != defaultCookieName+".2" || result[2].Value != "aaaaa" {
+			t.Fatalf("the cookie should be split into chunks")
+		}
+
+		r = httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		for _, c := range result {
+			r.AddCookie(c)
+		}
+		crw = NewChunkedReadWriter(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), 10)
+		c, err := crw.Cookie(defaultCookieName)
+		if err != nil {
+			t.Fatalf("get chunked cookie fail, %v", err)
+		}
+		if c.Name != defaultCookieName || c.Value != value {
+			t.Fatalf("the chunks should be reassembled")
+		}
+	})
+
+	t.Run("not chunked cookie", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(&http.Cookie{
+			Name:  defaultCookieName,
+			Value: value,
+		})
+		crw := NewChunkedReadWriter(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), 10)
+		c, err := crw.Cookie(defaultCookieName)
+		if err != nil || c.Value != value {
+			t.Fatalf("get not chunked cookie fail")
+		}
+		_, err = crw.Cookie("abc")
+		if err != http.ErrNoCookie {
+			t.Fatalf("get not exists cookie should return error")
+		}
+	})
+
+	t.Run("remove stale chunks", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		for i := 0; i < 3; i++ {
+			r.AddCookie(&http.Cookie{
+				Name:  getChunkName(defaultCookieName, i),
+				Value: "a",
+			})
+		}
+		w := httptest.NewRecorder()
+		crw := NewChunkedReadWriter(cookies.NewHTTPReadWriter(r, w), 10)
+		crw.SetCookie(&http.Cookie{
+			Name:  defaultCookieName,
+			Value: "b",
+		})
+		result := w.Result().Cookies()
+		if len(result) != 3 || result[0].Value != "b" || result[1].MaxAge >= 0 || result[2].MaxAge >= 0 {
+			t.Fatalf("the stale chunks should be removed")
+		}
+	})
+
+	t.Run("cookie store", func(t *testing.T) {
+		key := generateID()
+		data := bytes.Repeat([]byte("tree.xie"), 1000)
+		opts := &cookies.Options{
+			Keys: []string{
+				"tree.xie",
+			},
+		}
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		cs, _ := NewCookieStore(NewChunkedReadWriter(cookies.NewHTTPReadWriter(r, w), 0), opts)
+		cs.MaxSize = 16 * 1024
+		err := cs.Set(key, data, 60)
+		if err != nil {
+			t.Fatalf("set large data fail, %v", err)
+		}
+		result := w.Result().Cookies()
+		if len(result) != 3 {
+			t.Fatalf("large data should be split into chunks")
+		}
+		r = httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		for _, c := range result {
+			r.AddCookie(c)
+		}
+		cs, _ = NewCookieStore(NewChunkedReadWriter(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), 0), opts)
+		buf, _ := cs.Get(key)
+		if !bytes.Equal(buf, data) {
+			t.Fatalf("get large data fail")
+		}
+	})
+}
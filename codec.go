@@ -0,0 +1,163 @@
+package session
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// CodecIDJSON the id of json codec
+	CodecIDJSON byte = 1 + iota
+	// CodecIDGob the id of gob codec
+	CodecIDGob
+	// CodecIDBinary the id of binary codec
+	CodecIDBinary
+)
+
+var (
+	// ErrInvalidCodecID invalid codec id error
+	ErrInvalidCodecID = errors.New("invalid codec id")
+	// ErrCodecNotRegistered the codec of options should be registered
+	ErrCodecNotRegistered = errors.New("the codec is not registered")
+)
+
+type (
+	// JSONCodec json codec
+	JSONCodec struct{}
+	// GobCodec gob codec, the concrete types of value should be registered by gob.Register
+	GobCodec struct{}
+)
+
+var (
+	codecs     = make(map[byte]Codec)
+	codecMutex = sync.RWMutex{}
+)
+
+func init() {
+	gob.Register(M{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(map[string]string{})
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+
+	RegisterCodec(&JSONCodec{})
+	RegisterCodec(&GobCodec{})
+	RegisterCodec(&BinaryCodec{})
+}
+
+// RegisterCodec register the codec, the data whose version byte is the id of codec
+// will be decoded by it. The id 0, '{' and '[' are reserved for the data without version byte.
+func RegisterCodec(codec Codec) (err error) {
+	id := codec.ID()
+	if id == 0 || id == '{' || id == '[' {
+		return ErrInvalidCodecID
+	}
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	codecs[id] = codec
+	return
+}
+
+// getCodec get the codec by id
+func getCodec(id byte) Codec {
+	codecMutex.RLock()
+	defer codecMutex.RUnlock()
+	return codecs[id]
+}
+
+// ID the id of json codec
+func (c *JSONCodec) ID() byte {
+	return CodecIDJSON
+}
+
+// Marshal marshal the data to json
+func (c *JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal unmarshal the json data
+func (c *JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// ID the id of gob codec
+func (c *GobCodec) ID() byte {
+	return CodecIDGob
+}
+
+// Marshal marshal the data to gob
+func (c *GobCodec) Marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal unmarshal the gob data
+func (c *GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// encodeData encode the data with the codec, the id of codec is saved as the first byte
+func encodeData(codec Codec, v interface{}) (buf []byte, err error) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return
+	}
+	buf = make([]byte, 0, len(data)+1)
+	buf = append(buf, codec.ID())
+	buf = append(buf, data...)
+	return
+}
+
+// decodeData decode the data by the codec of version byte, if the codec is not found,
+// the data will be decoded by the json(which is saved without version byte)
+func decodeData(data []byte, v interface{}, j JSON) error {
+	if len(data) != 0 {
+		if codec := getCodec(data[0]); codec != nil {
+			return codec.Unmarshal(data[1:], v)
+		}
+	}
+	if j != nil {
+		return j.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// marshal encode the session data, if the codec of options is not set,
+// it will be encoded by json without version byte
+func (sess *Session) marshal(v interface{}) ([]byte, error) {
+	opts := sess.opts
+	if err := sess.checkCodec(); err != nil {
+		return nil, err
+	}
+	if opts.Codec != nil {
+		return encodeData(opts.Codec, v)
+	}
+	if opts.JSON != nil {
+		return opts.JSON.Marshal(v)
+	}
+	return json.Marshal(v)
+}
+
+// checkCodec check the codec of options is registered, the stores(e.g. the
+// atomic operation of store) decode the session data by the registered codecs
+func (sess *Session) checkCodec() error {
+	codec := sess.opts.Codec
+	if codec != nil && getCodec(codec.ID()) == nil {
+		return ErrCodecNotRegistered
+	}
+	return nil
+}
+
+// unmarshal decode the session data
+func (sess *Session) unmarshal(data []byte, v interface{}) error {
+	return decodeData(data, v, sess.opts.JSON)
+}
@@ -0,0 +1,167 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vicanso/cookies"
+)
+
+type customCodec struct {
+	JSONCodec
+}
+
+func (c *customCodec) ID() byte {
+	return '{'
+}
+
+type extCodec struct {
+	JSONCodec
+}
+
+func (c *extCodec) ID() byte {
+	return 0x7f
+}
+
+func TestCodec(t *testing.T) {
+	t.Run("register codec", func(t *testing.T) {
+		err := RegisterCodec(&customCodec{})
+		if err != ErrInvalidCodecID {
+			t.Fatalf("reserved id should not be registered")
+		}
+		if getCodec(CodecIDJSON) == nil || getCodec(CodecIDGob) == nil || getCodec(CodecIDBinary) == nil {
+			t.Fatalf("the built-in codecs should be registered")
+		}
+	})
+
+	t.Run("encode and decode", func(t *testing.T) {
+		now := time.Now()
+		for _, codec := range []Codec{
+			&JSONCodec{},
+			&GobCodec{},
+			&BinaryCodec{},
+		} {
+			buf, err := encodeData(codec, M{
+				"name":      "tree.xie",
+				"createdAt": now,
+			})
+			if err != nil {
+				t.Fatalf("encode data fail, %v", err)
+			}
+			if buf[0] != codec.ID() {
+				t.Fatalf("the version byte should be the id of codec")
+			}
+			m := make(M)
+			err = decodeData(buf, &m, nil)
+			if err != nil {
+				t.Fatalf("decode data fail, %v", err)
+			}
+			if m["name"] != "tree.xie" {
+				t.Fatalf("decode data fail")
+			}
+			if codec.ID() != CodecIDJSON && !m["createdAt"].(time.Time).Equal(now) {
+				t.Fatalf("the time should be preserved")
+			}
+		}
+	})
+
+	t.Run("decode data without version byte", func(t *testing.T) {
+		m := make(M)
+		err := decodeData([]byte(`{"name":"tree.xie"}`), &m, nil)
+		if err != nil || m["name"] != "tree.xie" {
+			t.Fatalf("decode json data without version byte fail, %v", err)
+		}
+	})
+
+	t.Run("migrate codec", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		id := generateID()
+		buf, _ := json.Marshal(M{
+			"name": "tree.xie",
+		})
+		ms.Set(id, buf, 60)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(&http.Cookie{
+			Name:  defaultCookieName,
+			Value: id,
+		})
+		sess := New(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), &Options{
+			Store: ms,
+			Codec: &BinaryCodec{},
+		})
+		sess.Fetch()
+		if sess.GetString("name") != "tree.xie" {
+			t.Fatalf("fetch json data fail")
+		}
+		sess.Set("count", 1)
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		buf, _ = ms.Get(id)
+		if buf[0] != CodecIDBinary {
+			t.Fatalf("the session should be saved by binary codec")
+		}
+
+		sess = New(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), &Options{
+			Store: ms,
+		})
+		sess.Fetch()
+		if sess.Get("count") != 1 {
+			t.Fatalf("fetch binary data fail")
+		}
+	})
+
+	t.Run("unregistered codec", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		opts := &Options{
+			Store: ms,
+			Codec: &extCodec{},
+		}
+		sess := New(cookies.NewHTTPReadWriter(r, w), opts)
+		_, err := sess.Fetch()
+		if err != ErrCodecNotRegistered {
+			t.Fatalf("fetch session with unregistered codec should return error")
+		}
+
+		RegisterCodec(&extCodec{})
+		// unregister the codec, so the test can be run repeatedly
+		defer func() {
+			codecMutex.Lock()
+			delete(codecs, (&extCodec{}).ID())
+			codecMutex.Unlock()
+		}()
+		sess = New(cookies.NewHTTPReadWriter(r, w), opts)
+		sess.Fetch()
+		sess.Set("count", 1)
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		buf, _ := ms.Get(sess.GetID())
+		if buf[0] != 0x7f {
+			t.Fatalf("the session should be saved by the codec of options")
+		}
+
+		r = httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(&http.Cookie{
+			Name:  defaultCookieName,
+			Value: sess.GetID(),
+		})
+		sess = New(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), opts)
+		_, err = sess.Fetch()
+		if err != nil {
+			t.Fatalf("fetch session fail, %v", err)
+		}
+		// the atomic operation of store decodes the data by the registered codec
+		count, err := sess.Incr("count", 1)
+		if err != nil || count != 2 {
+			t.Fatalf("incr session with registered codec fail, %v", err)
+		}
+	})
+}
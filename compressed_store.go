@@ -0,0 +1,164 @@
+package session
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// CompressionNone not compress
+	CompressionNone Compression = 0xc0 + iota
+	// CompressionGzip compress by gzip
+	CompressionGzip
+	// CompressionFlate compress by flate
+	CompressionFlate
+)
+
+const (
+	defaultCompressionThreshold = 1024
+)
+
+var (
+	// ErrInvalidCompression invalid compression error
+	ErrInvalidCompression = errors.New("invalid compression")
+)
+
+type (
+	// Compression the compression algorithm, it's saved as the header byte of data
+	Compression byte
+	// CompressedStore compressed store, it compresses the session data whose size
+	// is bigger than threshold and saves it to the wrapped store. The data without
+	// compression header(saved before using compressed store) is returned as it is.
+	CompressedStore struct {
+		store Store
+		// the compression algorithm, default is gzip
+		Compression Compression
+		// the data whose size is bigger than threshold will be compressed, default is 1024
+		Threshold int
+		// the compression level, default is flate.DefaultCompression
+		Level int
+	}
+)
+
+// compress compress the data, the compression is saved as the first byte
+func (cs *CompressedStore) compress(data []byte) (result []byte, err error) {
+	threshold := cs.Threshold
+	if threshold <= 0 {
+		threshold = defaultCompressionThreshold
+	}
+	compression := cs.Compression
+	if len(data) <= threshold {
+		compression = CompressionNone
+	}
+	if compression == CompressionNone {
+		result = make([]byte, 0, len(data)+1)
+		result = append(result, byte(CompressionNone))
+		return append(result, data...), nil
+	}
+	buf := bytes.NewBuffer([]byte{byte(compression)})
+	var w io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		w, err = gzip.NewWriterLevel(buf, cs.Level)
+	case CompressionFlate:
+		w, err = flate.NewWriter(buf, cs.Level)
+	default:
+		err = ErrInvalidCompression
+	}
+	if err != nil {
+		return
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return
+	}
+	err = w.Close()
+	if err != nil {
+		return
+	}
+	result = buf.Bytes()
+	return
+}
+
+// decompress decompress the data by the compression header
+func decompress(data []byte) (result []byte, err error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	var r io.ReadCloser
+	switch Compression(data[0]) {
+	case CompressionNone:
+		return data[1:], nil
+	case CompressionGzip:
+		r, err = gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return
+		}
+	case CompressionFlate:
+		r = flate.NewReader(bytes.NewReader(data[1:]))
+	default:
+		// the data isn't saved by compressed store
+		return data, nil
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// Get get the session data and decompress it
+func (cs *CompressedStore) Get(key string) ([]byte, error) {
+	return cs.GetContext(context.Background(), key)
+}
+
+// GetContext get the session data with context and decompress it
+func (cs *CompressedStore) GetContext(ctx context.Context, key string) (data []byte, err error) {
+	buf, err := getStoreContext(cs.store).GetContext(ctx, key)
+	if err != nil {
+		return
+	}
+	return decompress(buf)
+}
+
+// Set compress the session data and set it
+func (cs *CompressedStore) Set(key string, data []byte, ttl int) error {
+	return cs.SetContext(context.Background(), key, data, ttl)
+}
+
+// SetContext compress the session data and set it with context
+func (cs *CompressedStore) SetContext(ctx context.Context, key string, data []byte, ttl int) (err error) {
+	buf, err := cs.compress(data)
+	if err != nil {
+		return
+	}
+	return getStoreContext(cs.store).SetContext(ctx, key, buf, ttl)
+}
+
+// Destroy remove the session data
+func (cs *CompressedStore) Destroy(key string) error {
+	return cs.store.Destroy(key)
+}
+
+// DestroyContext remove the session data with context
+func (cs *CompressedStore) DestroyContext(ctx context.Context, key string) error {
+	return getStoreContext(cs.store).DestroyContext(ctx, key)
+}
+
+// NewCompressedStore create new compressed store instance
+func NewCompressedStore(store Store, compression Compression, threshold int) *CompressedStore {
+	if store == nil {
+		panic(errors.New("store can not be nil"))
+	}
+	if compression == 0 {
+		compression = CompressionGzip
+	}
+	return &CompressedStore{
+		store:       store,
+		Compression: compression,
+		Threshold:   threshold,
+		Level:       flate.DefaultCompression,
+	}
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressedStore(t *testing.T) {
+	key := generateID()
+	data := bytes.Repeat([]byte("tree.xie"), 200)
+	ttl := 300
+	ms, _ := NewMemoryStore(10)
+
+	t.Run("compress", func(t *testing.T) {
+		for _, compression := range []Compression{
+			CompressionGzip,
+			CompressionFlate,
+		} {
+			cs := NewCompressedStore(ms, compression, 100)
+			err := cs.Set(key, data, ttl)
+			if err != nil {
+				t.Fatalf("set data fail, %v", err)
+			}
+			raw, _ := ms.Get(key)
+			if Compression(raw[0]) != compression || len(raw) >= len(data) {
+				t.Fatalf("the data should be compressed")
+			}
+			buf, err := cs.Get(key)
+			if err != nil || !bytes.Equal(buf, data) {
+				t.Fatalf("get data fail after set, %v", err)
+			}
+		}
+	})
+
+	t.Run("below threshold", func(t *testing.T) {
+		cs := NewCompressedStore(ms, 0, 0)
+		err := cs.Set(key, []byte("tree.xie"), ttl)
+		if err != nil {
+			t.Fatalf("set data fail, %v", err)
+		}
+		raw, _ := ms.Get(key)
+		if Compression(raw[0]) != CompressionNone {
+			t.Fatalf("the data below threshold should not be compressed")
+		}
+		buf, _ := cs.Get(key)
+		if string(buf) != "tree.xie" {
+			t.Fatalf("get data fail after set")
+		}
+	})
+
+	t.Run("not compressed data", func(t *testing.T) {
+		cs := NewCompressedStore(ms, 0, 0)
+		ms.Set(key, []byte(`{"a":1}`), ttl)
+		buf, err := cs.Get(key)
+		if err != nil || string(buf) != `{"a":1}` {
+			t.Fatalf("the data without header should be returned as it is")
+		}
+	})
+
+	t.Run("invalid compression", func(t *testing.T) {
+		cs := NewCompressedStore(ms, Compression(1), 1)
+		err := cs.Set(key, data, ttl)
+		if err != ErrInvalidCompression {
+			t.Fatalf("should return invalid compression error")
+		}
+	})
+
+	t.Run("destroy", func(t *testing.T) {
+		cs := NewCompressedStore(ms, 0, 0)
+		err := cs.Destroy(key)
+		if err != nil {
+			t.Fatalf("destroy data fail, %v", err)
+		}
+		buf, err := cs.Get(key)
+		if err != nil || len(buf) != 0 {
+			t.Fatalf("shoud return empty bytes after destroy")
+		}
+	})
+}
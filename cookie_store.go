@@ -0,0 +1,160 @@
+package session
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/vicanso/cookies"
+)
+
+const (
+	defaultCookieStoreMaxSize = 4096
+)
+
+var (
+	// ErrKeysRequired keys required error
+	ErrKeysRequired = errors.New("keys are required")
+	// ErrCookieTooLarge cookie too large error
+	ErrCookieTooLarge = errors.New("cookie is too large")
+)
+
+type (
+	// CookieStore cookie store for session, the session data is encrypted
+	// by aes-gcm and saved in cookie
+	CookieStore struct {
+		cookies *cookies.Cookies
+		aeads   []cipher.AEAD
+		// the session id of the data which has been written to cookie
+		key string
+		// the cookie name of session data, default is sess.data
+		Name string
+		// the max size of cookie value, default is 4096
+		MaxSize int
+	}
+)
+
+// Get get the session data from cookie, the data which is expired or
+// not belong to the session id will be ignored
+func (cs *CookieStore) Get(key string) (data []byte, err error) {
+	value := cs.cookies.Get(cs.getName(), false)
+	if value == "" {
+		return
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		// the invalid cookie is ignored
+		err = nil
+		return
+	}
+	for _, aead := range cs.aeads {
+		plaintext, e := open(aead, buf, []byte(cs.getName()))
+		if e != nil {
+			continue
+		}
+		return decodeCookiePayload(key, plaintext), nil
+	}
+	return
+}
+
+// Set set the session data to cookie, the expired time and session id will be
+// embedded in the payload
+func (cs *CookieStore) Set(key string, data []byte, ttl int) (err error) {
+	var expiredAt int64
+	if ttl > 0 {
+		expiredAt = time.Now().Unix() + int64(ttl)
+	}
+	payload := encodeCookiePayload(key, data, expiredAt)
+	value := base64.RawURLEncoding.EncodeToString(seal(cs.aeads[0], payload, []byte(cs.getName())))
+	maxSize := cs.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultCookieStoreMaxSize
+	}
+	if len(value) > maxSize {
+		err = ErrCookieTooLarge
+		return
+	}
+	cookie := cs.cookies.CreateCookie(cs.getName(), value)
+	if ttl > 0 {
+		cookie.MaxAge = ttl
+		cookie.Expires = time.Unix(expiredAt, 0)
+	}
+	cs.cookies.Set(cookie, false)
+	cs.key = key
+	return
+}
+
+// Destroy remove the session data from cookie, the data which has been
+// overwritten by other session id(e.g. regenerate) will not be removed
+func (cs *CookieStore) Destroy(key string) (err error) {
+	if cs.key != "" && cs.key != key {
+		return
+	}
+	cookie := cs.cookies.CreateCookie(cs.getName(), "")
+	cookie.MaxAge = -1
+	cookie.Expires = time.Unix(0, 0)
+	cs.cookies.Set(cookie, false)
+	return
+}
+
+func (cs *CookieStore) getName() string {
+	if cs.Name == "" {
+		return defaultCookieName + ".data"
+	}
+	return cs.Name
+}
+
+// encodeCookiePayload encode the payload: expired at(8 bytes) + key length(uvarint) + key + data
+func encodeCookiePayload(key string, data []byte, expiredAt int64) []byte {
+	buf := make([]byte, 8+binary.MaxVarintLen64, 8+binary.MaxVarintLen64+len(key)+len(data))
+	binary.BigEndian.PutUint64(buf, uint64(expiredAt))
+	n := binary.PutUvarint(buf[8:], uint64(len(key)))
+	buf = append(buf[:8+n], key...)
+	return append(buf, data...)
+}
+
+// decodeCookiePayload decode the payload, return nil if it is expired or the key is not match
+func decodeCookiePayload(key string, payload []byte) []byte {
+	if len(payload) < 8 {
+		return nil
+	}
+	expiredAt := int64(binary.BigEndian.Uint64(payload))
+	if expiredAt != 0 && expiredAt < time.Now().Unix() {
+		return nil
+	}
+	size, n := binary.Uvarint(payload[8:])
+	if n <= 0 || uint64(len(payload)-8-n) < size {
+		return nil
+	}
+	offset := 8 + n + int(size)
+	if string(payload[8+n:offset]) != key {
+		return nil
+	}
+	return payload[offset:]
+}
+
+// NewCookieStore create new cookie store instance, the keys of options are used
+// to encrypt the session data, the first one is used for encryption and all of them
+// are used for decryption, so the keys can be rotated.
+func NewCookieStore(rw cookies.ReadWriter, opts *cookies.Options) (store *CookieStore, err error) {
+	if opts == nil || len(opts.Keys) == 0 {
+		err = ErrKeysRequired
+		return
+	}
+	aeads := make([]cipher.AEAD, len(opts.Keys))
+	for i, key := range opts.Keys {
+		hash := sha256.Sum256([]byte(key))
+		aeads[i], err = newAEAD(hash[:])
+		if err != nil {
+			return
+		}
+	}
+	store = &CookieStore{
+		cookies: cookies.New(rw, opts),
+		aeads:   aeads,
+	}
+	return
+}
@@ -0,0 +1,188 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vicanso/cookies"
+)
+
+func TestCookieStore(t *testing.T) {
+	key := generateID()
+	data := []byte("tree.xie")
+	ttl := 300
+	// newRequest create a request with the cookies set by response
+	newRequest := func(w *httptest.ResponseRecorder) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		for _, c := range w.Result().Cookies() {
+			r.AddCookie(c)
+		}
+		return r
+	}
+	opts := &cookies.Options{
+		Keys: []string{
+			"tree.xie",
+		},
+	}
+
+	t.Run("keys required", func(t *testing.T) {
+		_, err := NewCookieStore(nil, nil)
+		if err != ErrKeysRequired {
+			t.Fatalf("should return keys required error")
+		}
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		cs, err := NewCookieStore(cookies.NewHTTPReadWriter(r, w), opts)
+		if err != nil {
+			t.Fatalf("create cookie store fail, %v", err)
+		}
+		buf, err := cs.Get(key)
+		if err != nil || len(buf) != 0 {
+			t.Fatalf("shoud return empty bytes")
+		}
+		err = cs.Set(key, data, ttl)
+		if err != nil {
+			t.Fatalf("set data fail, %v", err)
+		}
+		value := w.HeaderMap["Set-Cookie"][0]
+		if !strings.HasPrefix(value, "sess.data=") || strings.Contains(value, "tree.xie") {
+			t.Fatalf("the data should be encrypted")
+		}
+
+		r = newRequest(w)
+		cs, _ = NewCookieStore(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), opts)
+		buf, err = cs.Get(key)
+		if err != nil {
+			t.Fatalf("get data fail after set, %v", err)
+		}
+		if !bytes.Equal(data, buf) {
+			t.Fatalf("the data is not the same after set")
+		}
+		buf, _ = cs.Get(generateID())
+		if len(buf) != 0 {
+			t.Fatalf("the data should not be got by other session id")
+		}
+
+		// rotate keys
+		cs, _ = NewCookieStore(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), &cookies.Options{
+			Keys: []string{
+				"vicanso",
+				"tree.xie",
+			},
+		})
+		buf, _ = cs.Get(key)
+		if !bytes.Equal(data, buf) {
+			t.Fatalf("the data should be decrypted by old key")
+		}
+		cs, _ = NewCookieStore(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), &cookies.Options{
+			Keys: []string{
+				"vicanso",
+			},
+		})
+		buf, _ = cs.Get(key)
+		if len(buf) != 0 {
+			t.Fatalf("the data should not be decrypted by invalid key")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		cs, _ := NewCookieStore(cookies.NewHTTPReadWriter(r, w), opts)
+		payload := encodeCookiePayload(key, data, 1)
+		value := seal(cs.aeads[0], payload, []byte(cs.getName()))
+		r.AddCookie(&http.Cookie{
+			Name:  cs.getName(),
+			Value: base64.RawURLEncoding.EncodeToString(value),
+		})
+		buf, err := cs.Get(key)
+		if err != nil || len(buf) != 0 {
+			t.Fatalf("expired data should be nil")
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		cs, _ := NewCookieStore(cookies.NewHTTPReadWriter(r, w), opts)
+		cs.MaxSize = 100
+		err := cs.Set(key, bytes.Repeat(data, 20), ttl)
+		if err != ErrCookieTooLarge {
+			t.Fatalf("should return too large error")
+		}
+	})
+
+	t.Run("destroy", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		cs, _ := NewCookieStore(cookies.NewHTTPReadWriter(r, w), opts)
+		err := cs.Destroy(key)
+		if err != nil {
+			t.Fatalf("destroy data fail, %v", err)
+		}
+		c := w.Result().Cookies()[0]
+		if c.Name != cs.getName() || c.MaxAge >= 0 {
+			t.Fatalf("destroy should remove the cookie")
+		}
+	})
+
+	t.Run("regenerate", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		rw := cookies.NewHTTPReadWriter(r, w)
+		cs, _ := NewCookieStore(rw, opts)
+		sess := New(rw, &Options{
+			Store: cs,
+		})
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+
+		r = newRequest(w)
+		w = httptest.NewRecorder()
+		rw = cookies.NewHTTPReadWriter(r, w)
+		cs, _ = NewCookieStore(rw, opts)
+		sess = New(rw, &Options{
+			Store: cs,
+		})
+		sess.Fetch()
+		prevID := sess.GetID()
+		err = sess.Regenerate()
+		if err != nil {
+			t.Fatalf("regenerate session fail, %v", err)
+		}
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		if sess.GetID() == prevID {
+			t.Fatalf("the session id should be regenerated")
+		}
+		for _, c := range w.Result().Cookies() {
+			if c.Name == cs.getName() && c.Value == "" {
+				t.Fatalf("the data of regenerated session should not be removed")
+			}
+		}
+
+		r = newRequest(w)
+		rw = cookies.NewHTTPReadWriter(r, httptest.NewRecorder())
+		cs, _ = NewCookieStore(rw, opts)
+		sess = New(rw, &Options{
+			Store: cs,
+		})
+		sess.Fetch()
+		if sess.GetString("name") != "tree.xie" {
+			t.Fatalf("the data should be kept after regenerate")
+		}
+	})
+}
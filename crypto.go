@@ -0,0 +1,36 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+var (
+	// ErrInvalidCiphertext invalid ciphertext error
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// newAEAD create aes-gcm aead by key
+func newAEAD(key []byte) (aead cipher.AEAD, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypt the data with random nonce, the nonce is prepended to the result
+func seal(aead cipher.AEAD, data, additionalData []byte) []byte {
+	nonce := randomBytes(aead.NonceSize())
+	return aead.Seal(nonce, nonce, data, additionalData)
+}
+
+// open decrypt the data sealed by seal
+func open(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	size := aead.NonceSize()
+	if len(data) < size+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+	return aead.Open(nil, data[:size], data[size:], additionalData)
+}
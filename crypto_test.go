@@ -0,0 +1,44 @@
+package session
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCrypto(t *testing.T) {
+	key := bytes.Repeat([]byte("a"), 32)
+	data := []byte("tree.xie")
+	aead, err := newAEAD(key)
+	if err != nil {
+		t.Fatalf("create aead fail, %v", err)
+	}
+
+	t.Run("seal and open", func(t *testing.T) {
+		ciphertext := seal(aead, data, nil)
+		if bytes.Contains(ciphertext, data) {
+			t.Fatalf("the data should be encrypted")
+		}
+		buf, err := open(aead, ciphertext, nil)
+		if err != nil || !bytes.Equal(buf, data) {
+			t.Fatalf("open data fail, %v", err)
+		}
+		_, err = open(aead, ciphertext, []byte("a"))
+		if err == nil {
+			t.Fatalf("open with other additional data should fail")
+		}
+	})
+
+	t.Run("invalid ciphertext", func(t *testing.T) {
+		_, err := open(aead, []byte("a"), nil)
+		if err != ErrInvalidCiphertext {
+			t.Fatalf("should return invalid ciphertext error")
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		_, err := newAEAD([]byte("a"))
+		if err == nil {
+			t.Fatalf("invalid key should return error")
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"context"
+	"sort"
+)
+
+// markDirty mark the keys of session data as modified, the key which
+// doesn't exist in session data will be treated as deleted when commit
+func (sess *Session) markDirty(keys ...string) {
+	if sess.dirty == nil {
+		sess.dirty = make(map[string]bool)
+	}
+	for _, key := range keys {
+		sess.dirty[key] = true
+	}
+	sess.modified = true
+}
+
+// Changes get the keys of session data which have been added or changed,
+// and the keys which have been deleted since fetch or the last commit
+func (sess *Session) Changes() (changed, deleted []string) {
+	for key := range sess.dirty {
+		if _, ok := sess.data[key]; ok {
+			changed = append(changed, key)
+		} else {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(deleted)
+	return
+}
+
+// getPartialStore get the partial store if the session can be committed partially,
+// the new, regenerated or versioned session should be rewritten completely
+func (sess *Session) getPartialStore() (PartialStore, bool) {
+	if !sess.stored || sess.prevCookieValue != "" || len(sess.dirty) == 0 {
+		return nil, false
+	}
+	if sess.opts.Versioned {
+		return nil, false
+	}
+	store, ok := sess.opts.Store.(PartialStore)
+	return store, ok
+}
+
+// savePartial only save the changes of session data to store
+func (sess *Session) savePartial(ctx context.Context, store PartialStore) (err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+	changed, deleted := sess.Changes()
+	fields := make(M, len(changed))
+	for _, key := range changed {
+		fields[key] = sess.data[key]
+	}
+	return store.SetFields(sess.cookieValue, fields, deleted, sess.GetMaxAge())
+}
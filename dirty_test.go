@@ -0,0 +1,145 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type partialStore struct {
+	*MemoryStore
+	fields  M
+	deleted []string
+}
+
+func (ps *partialStore) SetFields(key string, fields M, deleted []string, ttl int) (err error) {
+	ps.fields = fields
+	ps.deleted = deleted
+	buf, err := ps.Get(key)
+	if err != nil {
+		return
+	}
+	m := make(M)
+	err = json.Unmarshal(buf, &m)
+	if err != nil {
+		return
+	}
+	for k, v := range fields {
+		m[k] = v
+	}
+	for _, k := range deleted {
+		delete(m, k)
+	}
+	buf, err = json.Marshal(m)
+	if err != nil {
+		return
+	}
+	return ps.Set(key, buf, ttl)
+}
+
+func TestDirty(t *testing.T) {
+	opts := Options{
+		MaxAge: 60,
+	}
+	ms, _ := NewMemoryStore(10)
+	store := &partialStore{
+		MemoryStore: ms,
+	}
+
+	t.Run("changes", func(t *testing.T) {
+		sess := newHeaderSession(store, "", opts)
+		sess.Fetch()
+		changed, deleted := sess.Changes()
+		if len(changed) != 0 || len(deleted) != 0 {
+			t.Fatalf("fetched session should not have changes")
+		}
+		sess.Set("name", "tree.xie")
+		sess.SetMap(map[string]interface{}{
+			"age":  18,
+			"type": nil,
+		})
+		sess.SetPath("user.locale", "zh")
+		changed, deleted = sess.Changes()
+		if !reflect.DeepEqual(changed, []string{UpdatedAt, "age", "name", "user"}) ||
+			!reflect.DeepEqual(deleted, []string{"type"}) {
+			t.Fatalf("get changes of session fail")
+		}
+	})
+
+	t.Run("partial commit", func(t *testing.T) {
+		id := generateID()
+		buf, _ := json.Marshal(M{
+			"name": "tree.xie",
+			"age":  18,
+			"type": "vip",
+		})
+		store.Set(id, buf, 60)
+		store.fields = nil
+
+		sess := newHeaderSession(store, id, opts)
+		sess.Fetch()
+		sess.Set("age", 20)
+		sess.Set("type", nil)
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session partially fail, %v", err)
+		}
+		if len(store.fields) != 2 || store.fields["age"] != 20 ||
+			!reflect.DeepEqual(store.deleted, []string{"type"}) {
+			t.Fatalf("only the changes should be committed")
+		}
+		changed, deleted := sess.Changes()
+		if len(changed) != 0 || len(deleted) != 0 {
+			t.Fatalf("the changes should be reset after commit")
+		}
+
+		buf, _ = store.Get(id)
+		m := make(M)
+		json.Unmarshal(buf, &m)
+		if m["name"] != "tree.xie" || m["age"].(float64) != 20 || m["type"] != nil {
+			t.Fatalf("the data is wrong after partial commit")
+		}
+	})
+
+	t.Run("full commit", func(t *testing.T) {
+		store.fields = nil
+		// new session
+		sess := newHeaderSession(store, "", opts)
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit new session fail, %v", err)
+		}
+		if store.fields != nil {
+			t.Fatalf("new session should be committed completely")
+		}
+
+		// regenerated session
+		id := sess.cookieValue
+		sess = newHeaderSession(store, id, opts)
+		sess.Fetch()
+		sess.Regenerate()
+		sess.Set("name", "vicanso")
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit regenerated session fail, %v", err)
+		}
+		if store.fields != nil {
+			t.Fatalf("regenerated session should be committed completely")
+		}
+
+		// not partial store
+		id = sess.cookieValue
+		sess = newHeaderSession(ms, id, opts)
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		if store.fields != nil {
+			t.Fatalf("the store isn't partial store")
+		}
+	})
+}
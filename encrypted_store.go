@@ -0,0 +1,162 @@
+package session
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const (
+	encryptedStoreVersion = 1
+	// version(1 byte) + key id(1 byte) + expired at(8 bytes)
+	encryptedStoreHeaderSize = 10
+)
+
+var (
+	// ErrInvalidKeySize invalid key size error
+	ErrInvalidKeySize = errors.New("the size of key should be 32")
+	// ErrDuplicateKeyID duplicate key id error
+	ErrDuplicateKeyID = errors.New("duplicate key id")
+	// ErrKeyNotFound key not found error
+	ErrKeyNotFound = errors.New("the key of ciphertext is not found")
+)
+
+type (
+	// EncryptionKey the key for encrypted store
+	EncryptionKey struct {
+		// the id of key, it's saved in the ciphertext header
+		ID byte
+		// the aes-256 key, it should be 32 bytes
+		Key []byte
+	}
+	// EncryptedStore encrypted store, it encrypts the session data by aes-256-gcm
+	// and saves it to the wrapped store
+	EncryptedStore struct {
+		store   Store
+		aeads   map[byte]cipher.AEAD
+		primary byte
+		// re-encrypt the data with the primary key when it's read and
+		// encrypted by other key
+		ReEncrypt bool
+	}
+)
+
+// encrypt encrypt the data with primary key
+func (es *EncryptedStore) encrypt(key string, data []byte, ttl int) []byte {
+	var expiredAt int64
+	if ttl > 0 {
+		expiredAt = time.Now().Unix() + int64(ttl)
+	}
+	header := make([]byte, encryptedStoreHeaderSize)
+	header[0] = encryptedStoreVersion
+	header[1] = es.primary
+	binary.BigEndian.PutUint64(header[2:], uint64(expiredAt))
+	ciphertext := seal(es.aeads[es.primary], data, append(header, key...))
+	return append(header, ciphertext...)
+}
+
+// decrypt decrypt the data, return the key id and the expired at of data
+func (es *EncryptedStore) decrypt(key string, buf []byte) (data []byte, keyID byte, expiredAt int64, err error) {
+	if len(buf) < encryptedStoreHeaderSize || buf[0] != encryptedStoreVersion {
+		err = ErrInvalidCiphertext
+		return
+	}
+	keyID = buf[1]
+	aead, ok := es.aeads[keyID]
+	if !ok {
+		err = ErrKeyNotFound
+		return
+	}
+	expiredAt = int64(binary.BigEndian.Uint64(buf[2:encryptedStoreHeaderSize]))
+	header := make([]byte, encryptedStoreHeaderSize, encryptedStoreHeaderSize+len(key))
+	copy(header, buf)
+	data, err = open(aead, buf[encryptedStoreHeaderSize:], append(header, key...))
+	return
+}
+
+// Get get the session data and decrypt it
+func (es *EncryptedStore) Get(key string) ([]byte, error) {
+	return es.GetContext(context.Background(), key)
+}
+
+// GetContext get the session data with context and decrypt it
+func (es *EncryptedStore) GetContext(ctx context.Context, key string) (data []byte, err error) {
+	store := getStoreContext(es.store)
+	buf, err := store.GetContext(ctx, key)
+	if err != nil || len(buf) == 0 {
+		return
+	}
+	data, keyID, expiredAt, err := es.decrypt(key, buf)
+	if err != nil {
+		return
+	}
+	if !es.ReEncrypt || keyID == es.primary {
+		return
+	}
+	ttl := 0
+	if expiredAt != 0 {
+		ttl = int(expiredAt - time.Now().Unix())
+		// the data is expired
+		if ttl <= 0 {
+			return
+		}
+	}
+	err = store.SetContext(ctx, key, es.encrypt(key, data, ttl), ttl)
+	return
+}
+
+// Set encrypt the session data and set it
+func (es *EncryptedStore) Set(key string, data []byte, ttl int) error {
+	return es.SetContext(context.Background(), key, data, ttl)
+}
+
+// SetContext encrypt the session data and set it with context
+func (es *EncryptedStore) SetContext(ctx context.Context, key string, data []byte, ttl int) error {
+	return getStoreContext(es.store).SetContext(ctx, key, es.encrypt(key, data, ttl), ttl)
+}
+
+// Destroy remove the session data
+func (es *EncryptedStore) Destroy(key string) error {
+	return es.store.Destroy(key)
+}
+
+// DestroyContext remove the session data with context
+func (es *EncryptedStore) DestroyContext(ctx context.Context, key string) error {
+	return getStoreContext(es.store).DestroyContext(ctx, key)
+}
+
+// NewEncryptedStore create new encrypted store instance, the first key is
+// the primary key which is used for encryption, all of the keys are used for
+// decryption, so the keys can be rotated without invalidating the live sessions.
+func NewEncryptedStore(store Store, keys ...EncryptionKey) (es *EncryptedStore, err error) {
+	if store == nil {
+		panic(errors.New("store can not be nil"))
+	}
+	if len(keys) == 0 {
+		err = ErrKeysRequired
+		return
+	}
+	aeads := make(map[byte]cipher.AEAD)
+	for _, key := range keys {
+		if len(key.Key) != 32 {
+			err = ErrInvalidKeySize
+			return
+		}
+		if aeads[key.ID] != nil {
+			err = ErrDuplicateKeyID
+			return
+		}
+		aeads[key.ID], err = newAEAD(key.Key)
+		if err != nil {
+			return
+		}
+	}
+	es = &EncryptedStore{
+		store:   store,
+		aeads:   aeads,
+		primary: keys[0].ID,
+	}
+	return
+}
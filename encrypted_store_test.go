@@ -0,0 +1,108 @@
+package session
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	key := generateID()
+	data := []byte("tree.xie")
+	ttl := 300
+	oldKey := EncryptionKey{
+		ID:  1,
+		Key: bytes.Repeat([]byte("a"), 32),
+	}
+	newKey := EncryptionKey{
+		ID:  2,
+		Key: bytes.Repeat([]byte("b"), 32),
+	}
+	ms, _ := NewMemoryStore(10)
+
+	t.Run("invalid keys", func(t *testing.T) {
+		_, err := NewEncryptedStore(ms)
+		if err != ErrKeysRequired {
+			t.Fatalf("should return keys required error")
+		}
+		_, err = NewEncryptedStore(ms, EncryptionKey{
+			Key: []byte("a"),
+		})
+		if err != ErrInvalidKeySize {
+			t.Fatalf("should return invalid key size error")
+		}
+		_, err = NewEncryptedStore(ms, oldKey, oldKey)
+		if err != ErrDuplicateKeyID {
+			t.Fatalf("should return duplicate key id error")
+		}
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		es, err := NewEncryptedStore(ms, oldKey)
+		if err != nil {
+			t.Fatalf("create encrypted store fail, %v", err)
+		}
+		buf, err := es.Get(key)
+		if err != nil || len(buf) != 0 {
+			t.Fatalf("shoud return empty bytes")
+		}
+		err = es.Set(key, data, ttl)
+		if err != nil {
+			t.Fatalf("set data fail, %v", err)
+		}
+		raw, _ := ms.Get(key)
+		if bytes.Contains(raw, data) || raw[1] != oldKey.ID {
+			t.Fatalf("the data should be encrypted")
+		}
+		buf, err = es.Get(key)
+		if err != nil || !bytes.Equal(buf, data) {
+			t.Fatalf("get data fail after set, %v", err)
+		}
+
+		// the ciphertext is bound to the key
+		ms.Set("abc", raw, ttl)
+		_, err = es.Get("abc")
+		if err == nil {
+			t.Fatalf("get data which is moved to other key should fail")
+		}
+	})
+
+	t.Run("rotate keys", func(t *testing.T) {
+		es, _ := NewEncryptedStore(ms, newKey, oldKey)
+		buf, err := es.Get(key)
+		if err != nil || !bytes.Equal(buf, data) {
+			t.Fatalf("get data encrypted by old key fail, %v", err)
+		}
+		raw, _ := ms.Get(key)
+		if raw[1] != oldKey.ID {
+			t.Fatalf("the data should not be re-encrypted")
+		}
+
+		es.ReEncrypt = true
+		buf, err = es.Get(key)
+		if err != nil || !bytes.Equal(buf, data) {
+			t.Fatalf("get data encrypted by old key fail, %v", err)
+		}
+		raw, _ = ms.Get(key)
+		if raw[1] != newKey.ID {
+			t.Fatalf("the data should be re-encrypted by new key")
+		}
+
+		es, _ = NewEncryptedStore(ms, oldKey)
+		_, err = es.Get(key)
+		if err != ErrKeyNotFound {
+			t.Fatalf("should return key not found error")
+		}
+	})
+
+	t.Run("destroy", func(t *testing.T) {
+		es, _ := NewEncryptedStore(ms, newKey)
+		err := es.Destroy(key)
+		if err != nil {
+			t.Fatalf("destroy data fail, %v", err)
+		}
+		buf, err := es.Get(key)
+		if err != nil || len(buf) != 0 {
+			t.Fatalf("shoud return empty bytes after destroy")
+		}
+	})
+}
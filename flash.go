@@ -0,0 +1,69 @@
+package session
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// getFlashes get the flash messages of session
+func (sess *Session) getFlashes() map[string]interface{} {
+	m, _ := toMap(sess.data[Flash])
+	return m
+}
+
+// AddFlash add the flash message to the category, it will be removed after read.
+// The typed value is preserved if the codec supports it(e.g. BinaryCodec)
+func (sess *Session) AddFlash(category string, value interface{}) (err error) {
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	flashes := sess.getFlashes()
+	if flashes == nil {
+		flashes = make(map[string]interface{})
+	}
+	flashes[category] = append(cast.ToSlice(flashes[category]), value)
+	sess.data[Flash] = flashes
+	sess.data[UpdatedAt] = time.Now().Format(time.RFC3339)
+	sess.markDirty(Flash, UpdatedAt)
+	return
+}
+
+// Flashes get and remove the flash messages of the category,
+// the session will be modified if there are flash messages
+func (sess *Session) Flashes(category string) []interface{} {
+	if !sess.fetched {
+		return nil
+	}
+	flashes := sess.getFlashes()
+	values, ok := flashes[category]
+	if !ok {
+		return nil
+	}
+	delete(flashes, category)
+	if len(flashes) == 0 {
+		delete(sess.data, Flash)
+	} else {
+		sess.data[Flash] = flashes
+	}
+	sess.markDirty(Flash)
+	return cast.ToSlice(values)
+}
+
+// BindFlashes get and remove the flash messages of the category,
+// and bind them to v, which should be a pointer of slice
+func (sess *Session) BindFlashes(category string, v interface{}) (err error) {
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	values := sess.Flashes(category)
+	if values == nil {
+		return
+	}
+	buf, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+	return json.Unmarshal(buf, v)
+}
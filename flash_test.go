@@ -0,0 +1,88 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vicanso/cookies"
+)
+
+type flashMessage struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func TestFlash(t *testing.T) {
+	t.Run("add and read flashes", func(t *testing.T) {
+		sess := Mock(M{
+			"fetched": true,
+			"data":    M{},
+		})
+		err := sess.AddFlash("info", "saved!")
+		if err != nil {
+			t.Fatalf("add flash fail, %v", err)
+		}
+		sess.AddFlash("info", "done!")
+		sess.AddFlash("error", "fail")
+		values := sess.Flashes("info")
+		if len(values) != 2 || values[0] != "saved!" || values[1] != "done!" {
+			t.Fatalf("get flashes fail")
+		}
+		if sess.Flashes("info") != nil {
+			t.Fatalf("the flashes should be removed after read")
+		}
+		if len(sess.Flashes("error")) != 1 || sess.GetData()[Flash] != nil {
+			t.Fatalf("the flash key should be removed when all flashes are read")
+		}
+		err = Mock(M{}).AddFlash("info", "a")
+		if err != ErrNotFetched {
+			t.Fatalf("the session should fetch before add flash")
+		}
+	})
+
+	t.Run("flashes are removed after commit", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		sess := New(cookies.NewHTTPReadWriter(r, w), &Options{
+			Store: ms,
+		})
+		sess.Fetch()
+		sess.AddFlash("error", &flashMessage{
+			Field:   "account",
+			Message: "required",
+		})
+		sess.Commit()
+
+		newSession := func() *Session {
+			r = httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+			for _, c := range w.Result().Cookies() {
+				r.AddCookie(c)
+			}
+			sess := New(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), &Options{
+				Store: ms,
+			})
+			sess.Fetch()
+			return sess
+		}
+		sess = newSession()
+		messages := make([]flashMessage, 0)
+		err := sess.BindFlashes("error", &messages)
+		if err != nil {
+			t.Fatalf("bind flashes fail, %v", err)
+		}
+		if len(messages) != 1 || messages[0].Field != "account" {
+			t.Fatalf("bind flashes fail")
+		}
+		if !sess.modified {
+			t.Fatalf("the session should be modified after reading flashes")
+		}
+		sess.Commit()
+
+		sess = newSession()
+		if sess.Flashes("error") != nil {
+			t.Fatalf("the flashes should be removed after commit")
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	pathSeparator = "."
+)
+
+var (
+	// ErrInvalidPath invalid path error
+	ErrInvalidPath = errors.New("the path is invalid")
+)
+
+// toMap convert the value to map[string]interface{}
+func toMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case M:
+		return map[string]interface{}(m), true
+	case map[string]interface{}:
+		return m, true
+	}
+	return nil, false
+}
+
+// lookup get the value by key, if the key isn't found and it's a
+// dotted path, the value will be got from the nested map
+func (sess *Session) lookup(key string) (interface{}, bool) {
+	v, ok := sess.data[key]
+	if ok || !strings.Contains(key, pathSeparator) {
+		return v, ok
+	}
+	var current interface{} = map[string]interface{}(sess.data)
+	for _, k := range strings.Split(key, pathSeparator) {
+		m, ok := toMap(current)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[k]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// SetPath set the value to session's data by dotted path, the nested map will be
+// created if it doesn't exist. If the value is nil, it will be removed.
+func (sess *Session) SetPath(path string, value interface{}) (err error) {
+	if path == "" {
+		return
+	}
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	keys := strings.Split(path, pathSeparator)
+	m := map[string]interface{}(sess.data)
+	for _, k := range keys[:len(keys)-1] {
+		v, exists := m[k]
+		if !exists {
+			// no need to create the nested map for deletion
+			if value == nil {
+				return
+			}
+			next := make(map[string]interface{})
+			m[k] = next
+			m = next
+			continue
+		}
+		next, ok := toMap(v)
+		if !ok {
+			return ErrInvalidPath
+		}
+		m = next
+	}
+	key := keys[len(keys)-1]
+	if value == nil {
+		delete(m, key)
+	} else {
+		m[key] = value
+	}
+	sess.data[UpdatedAt] = time.Now().Format(time.RFC3339)
+	sess.markDirty(keys[0], UpdatedAt)
+	return
+}
+
+// DeletePath remove the value of session's data by dotted path
+func (sess *Session) DeletePath(path string) error {
+	return sess.SetPath(path, nil)
+}
+
+// getValue get the value for conversion
+func (sess *Session) getValue(key string) (v interface{}, exists bool, err error) {
+	if !sess.fetched {
+		err = ErrNotFetched
+		return
+	}
+	v, exists = sess.lookup(key)
+	// cast doesn't support the named map type
+	if m, ok := v.(M); ok {
+		v = map[string]interface{}(m)
+	}
+	return
+}
+
+// GetTime get time data from session's data
+func (sess *Session) GetTime(key string) time.Time {
+	v, _ := sess.GetTimeE(key)
+	return v
+}
+
+// GetDuration get duration data from session's data
+func (sess *Session) GetDuration(key string) time.Duration {
+	v, _ := sess.GetDurationE(key)
+	return v
+}
+
+// GetInt64 get int64 data from session's data
+func (sess *Session) GetInt64(key string) int64 {
+	v, _ := sess.GetInt64E(key)
+	return v
+}
+
+// GetUint64 get uint64 data from session's data
+func (sess *Session) GetUint64(key string) uint64 {
+	v, _ := sess.GetUint64E(key)
+	return v
+}
+
+// GetStringMap get map data from session's data
+func (sess *Session) GetStringMap(key string) map[string]interface{} {
+	v, _ := sess.GetStringMapE(key)
+	return v
+}
+
+// GetStringMapString get string map data from session's data
+func (sess *Session) GetStringMapString(key string) map[string]string {
+	v, _ := sess.GetStringMapStringE(key)
+	return v
+}
+
+// GetIntSlice get int slice data from session's data
+func (sess *Session) GetIntSlice(key string) []int {
+	v, _ := sess.GetIntSliceE(key)
+	return v
+}
+
+// GetBoolE get bool data from session's data, return error if the conversion fails
+func (sess *Session) GetBoolE(key string) (b bool, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToBoolE(v)
+}
+
+// GetStringE get string data from session's data, return error if the conversion fails
+func (sess *Session) GetStringE(key string) (s string, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToStringE(v)
+}
+
+// GetIntE get int data from session's data, return error if the conversion fails
+func (sess *Session) GetIntE(key string) (i int, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToIntE(v)
+}
+
+// GetInt64E get int64 data from session's data, return error if the conversion fails
+func (sess *Session) GetInt64E(key string) (i int64, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToInt64E(v)
+}
+
+// GetUint64E get uint64 data from session's data, return error if the conversion fails
+func (sess *Session) GetUint64E(key string) (u uint64, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToUint64E(v)
+}
+
+// GetFloat64E get float64 data from session's data, return error if the conversion fails
+func (sess *Session) GetFloat64E(key string) (f float64, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToFloat64E(v)
+}
+
+// GetTimeE get time data from session's data, return error if the conversion fails
+func (sess *Session) GetTimeE(key string) (t time.Time, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToTimeE(v)
+}
+
+// GetDurationE get duration data from session's data, return error if the conversion fails
+func (sess *Session) GetDurationE(key string) (d time.Duration, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToDurationE(v)
+}
+
+// GetStringSliceE get string slice data from session's data, return error if the conversion fails
+func (sess *Session) GetStringSliceE(key string) (s []string, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToStringSliceE(v)
+}
+
+// GetIntSliceE get int slice data from session's data, return error if the conversion fails
+func (sess *Session) GetIntSliceE(key string) (s []int, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToIntSliceE(v)
+}
+
+// GetStringMapE get map data from session's data, return error if the conversion fails
+func (sess *Session) GetStringMapE(key string) (m map[string]interface{}, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToStringMapE(v)
+}
+
+// GetStringMapStringE get string map data from session's data, return error if the conversion fails
+func (sess *Session) GetStringMapStringE(key string) (m map[string]string, err error) {
+	v, exists, err := sess.getValue(key)
+	if err != nil || !exists {
+		return
+	}
+	return cast.ToStringMapStringE(v)
+}
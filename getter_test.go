@@ -0,0 +1,137 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetter(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	newSession := func() *Session {
+		return Mock(M{
+			"fetched": true,
+			"data": M{
+				"time":     now,
+				"duration": "1m",
+				"int64":    float64(1 << 40),
+				"uint64":   "100",
+				"map": M{
+					"a": 1,
+				},
+				"stringMap": map[string]interface{}{
+					"a": "1",
+				},
+				"ints": []interface{}{
+					float64(1),
+					float64(2),
+				},
+				"user": map[string]interface{}{
+					"profile": M{
+						"locale": "zh",
+					},
+				},
+				"a.b": "c",
+			},
+		})
+	}
+
+	t.Run("get(type) function", func(t *testing.T) {
+		sess := newSession()
+		if sess.GetTime("time").Format(time.RFC3339) != now {
+			t.Fatalf("get time fail")
+		}
+		if sess.GetDuration("duration") != time.Minute {
+			t.Fatalf("get duration fail")
+		}
+		if sess.GetInt64("int64") != 1<<40 {
+			t.Fatalf("get int64 fail")
+		}
+		if sess.GetUint64("uint64") != 100 {
+			t.Fatalf("get uint64 fail")
+		}
+		if sess.GetStringMap("map")["a"] != 1 {
+			t.Fatalf("get string map fail")
+		}
+		if sess.GetStringMapString("stringMap")["a"] != "1" {
+			t.Fatalf("get string map string fail")
+		}
+		ints := sess.GetIntSlice("ints")
+		if len(ints) != 2 || ints[1] != 2 {
+			t.Fatalf("get int slice fail")
+		}
+		if !sess.GetTime("notFound").IsZero() || sess.GetIntSlice("notFound") != nil {
+			t.Fatalf("get not found data should return zero value")
+		}
+	})
+
+	t.Run("get(type)E function", func(t *testing.T) {
+		sess := newSession()
+		_, err := sess.GetIntE("time")
+		if err == nil {
+			t.Fatalf("get int from time string should return error")
+		}
+		_, err = sess.GetTimeE("map")
+		if err == nil {
+			t.Fatalf("get time from map should return error")
+		}
+		i, err := sess.GetIntE("notFound")
+		if err != nil || i != 0 {
+			t.Fatalf("get not found data should return zero value")
+		}
+		s, err := sess.GetStringE("user.profile.locale")
+		if err != nil || s != "zh" {
+			t.Fatalf("get string by path fail")
+		}
+		_, err = Mock(M{}).GetBoolE("a")
+		if err != ErrNotFetched {
+			t.Fatalf("the session should fetch before get")
+		}
+	})
+
+	t.Run("path", func(t *testing.T) {
+		sess := newSession()
+		if sess.Get("user.profile.locale") != "zh" {
+			t.Fatalf("get data by path fail")
+		}
+		if sess.Get("a.b") != "c" {
+			t.Fatalf("the key with dot should be got first")
+		}
+		if sess.Get("user.profile.notFound") != nil || sess.Get("time.a") != nil {
+			t.Fatalf("get not found path should return nil")
+		}
+
+		err := sess.SetPath("user.profile.theme", "dark")
+		if err != nil {
+			t.Fatalf("set data by path fail, %v", err)
+		}
+		err = sess.SetPath("user.settings.lang", "en")
+		if err != nil {
+			t.Fatalf("set data by path fail, %v", err)
+		}
+		if sess.GetString("user.profile.theme") != "dark" ||
+			sess.GetString("user.settings.lang") != "en" ||
+			!sess.modified {
+			t.Fatalf("set data by path fail")
+		}
+		err = sess.SetPath("time.a", 1)
+		if err != ErrInvalidPath {
+			t.Fatalf("set data to not map value should return error")
+		}
+
+		err = sess.DeletePath("user.profile.locale")
+		if err != nil {
+			t.Fatalf("delete data by path fail, %v", err)
+		}
+		if sess.Get("user.profile.locale") != nil || sess.Get("user.profile.theme") != "dark" {
+			t.Fatalf("delete data by path fail")
+		}
+		err = sess.DeletePath("notFound.a")
+		if err != nil || sess.Get("notFound") != nil {
+			t.Fatalf("delete not found path should be ignored")
+		}
+		err = Mock(M{}).SetPath("a.b", 1)
+		if err != ErrNotFetched {
+			t.Fatalf("the session should fetch before set path")
+		}
+	})
+}
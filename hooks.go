@@ -0,0 +1,76 @@
+package session
+
+const (
+	// ReasonFetch the session is fetched from store
+	ReasonFetch = "fetch"
+	// ReasonCommit the session is committed to store
+	ReasonCommit = "commit"
+	// ReasonRegenerate the session id is regenerated
+	ReasonRegenerate = "regenerate"
+	// ReasonRefresh the session is refreshed
+	ReasonRefresh = "refresh"
+	// ReasonRolling the ttl of session is extended by rolling
+	ReasonRolling = "rolling"
+	// ReasonDestroy the session is destroyed
+	ReasonDestroy = "destroy"
+	// ReasonEvict the session is evicted by the limit of user sessions
+	ReasonEvict = "evict"
+	// ReasonDestroyUser the session is destroyed with all the sessions of user
+	ReasonDestroyUser = "destroy_user"
+	// ReasonIdleTimeout the session is idle timeout
+	ReasonIdleTimeout = "idle_timeout"
+	// ReasonAbsoluteTimeout the session is absolute timeout
+	ReasonAbsoluteTimeout = "absolute_timeout"
+	// ReasonExpired the session is expired in store
+	ReasonExpired = "expired"
+)
+
+type (
+	// Hook the lifecycle hook of session, the data is a snapshot of session data
+	Hook func(id string, data M, reason string)
+	// Hooks the lifecycle hooks of session
+	Hooks struct {
+		// OnCreate is called when the new session is committed
+		OnCreate Hook
+		// OnLoad is called when the stored session is fetched
+		OnLoad Hook
+		// OnCommit is called when the session is committed
+		OnCommit Hook
+		// OnRegenerate is called with the new id when the session id is regenerated
+		OnRegenerate Hook
+		// OnRefresh is called when the session is refreshed or rolled
+		OnRefresh Hook
+		// OnDestroy is called when the session is destroyed
+		OnDestroy Hook
+		// OnExpire is called when the session is expired
+		OnExpire Hook
+	}
+)
+
+// copyData copy the session data, the nested value isn't copied
+func copyData(m M) M {
+	if m == nil {
+		return nil
+	}
+	data := make(M, len(m))
+	for k, v := range m {
+		data[k] = v
+	}
+	return data
+}
+
+// emit call the hook with the snapshot of session data
+func emit(hook Hook, id string, data M, reason string) {
+	if hook == nil {
+		return
+	}
+	hook(id, copyData(data), reason)
+}
+
+// getHooks get the hooks of session, it won't be nil
+func (sess *Session) getHooks() *Hooks {
+	if sess.opts == nil || sess.opts.Hooks == nil {
+		return &Hooks{}
+	}
+	return sess.opts.Hooks
+}
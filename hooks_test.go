@@ -0,0 +1,182 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type hookEvent struct {
+	hook   string
+	id     string
+	data   M
+	reason string
+}
+
+func newHooks(events *[]hookEvent) *Hooks {
+	record := func(hook string) Hook {
+		return func(id string, data M, reason string) {
+			*events = append(*events, hookEvent{
+				hook:   hook,
+				id:     id,
+				data:   data,
+				reason: reason,
+			})
+		}
+	}
+	return &Hooks{
+		OnCreate:     record("create"),
+		OnLoad:       record("load"),
+		OnCommit:     record("commit"),
+		OnRegenerate: record("regenerate"),
+		OnRefresh:    record("refresh"),
+		OnDestroy:    record("destroy"),
+		OnExpire:     record("expire"),
+	}
+}
+
+func getHookNames(events []hookEvent) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.hook + ":" + e.reason
+	}
+	return names
+}
+
+func TestHooks(t *testing.T) {
+	ms, _ := NewMemoryStore(10)
+
+	t.Run("copy data", func(t *testing.T) {
+		if copyData(nil) != nil {
+			t.Fatalf("copy nil data should be nil")
+		}
+		m := M{
+			"name": "tree.xie",
+		}
+		data := copyData(m)
+		data["name"] = "vicanso"
+		if m["name"] != "tree.xie" {
+			t.Fatalf("the data should be copied")
+		}
+		// no hooks
+		sess := newHeaderSession(ms, "", Options{
+			MaxAge: 60,
+		})
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session without hooks fail, %v", err)
+		}
+	})
+
+	t.Run("lifecycle", func(t *testing.T) {
+		events := make([]hookEvent, 0)
+		hooks := newHooks(&events)
+		sess := newHeaderSession(ms, "", Options{
+			MaxAge: 60,
+			Hooks:  hooks,
+		})
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		sess.Commit()
+		id := sess.GetID()
+		if events[0].id != id || events[0].data["name"] != "tree.xie" {
+			t.Fatalf("the id and data should be passed to hook")
+		}
+
+		sess = newHeaderSession(ms, id, Options{
+			MaxAge: 60,
+			Hooks:  hooks,
+		})
+		sess.Fetch()
+		sess.Refresh()
+		sess.Regenerate()
+		sess.Commit()
+		sess.Destroy()
+		expected := []string{
+			"create:" + ReasonCommit,
+			"commit:" + ReasonCommit,
+			"load:" + ReasonFetch,
+			"refresh:" + ReasonRefresh,
+			"regenerate:" + ReasonRegenerate,
+			"commit:" + ReasonCommit,
+			"destroy:" + ReasonDestroy,
+		}
+		if !reflect.DeepEqual(getHookNames(events), expected) {
+			t.Fatalf("the hooks are not called as expected, %v", getHookNames(events))
+		}
+		if events[4].id != sess.GetID() || events[4].id == id {
+			t.Fatalf("the regenerate hook should be called with the new id")
+		}
+		events[6].data["name"] = "vicanso"
+		if sess.GetString("name") != "" || events[6].data["name"] != "vicanso" {
+			t.Fatalf("the data of hook should be a snapshot")
+		}
+	})
+
+	t.Run("rolling", func(t *testing.T) {
+		events := make([]hookEvent, 0)
+		id := generateID()
+		buf, _ := json.Marshal(M{
+			CreatedAt: time.Now().Format(time.RFC3339),
+		})
+		ms.Set(id, buf, 60)
+		sess := newHeaderSession(ms, id, Options{
+			MaxAge:  60,
+			Hooks:   newHooks(&events),
+			Rolling: true,
+		})
+		sess.Fetch()
+		sess.Commit()
+		expected := []string{
+			"load:" + ReasonFetch,
+			"refresh:" + ReasonRolling,
+		}
+		if !reflect.DeepEqual(getHookNames(events), expected) {
+			t.Fatalf("the refresh hook should be called by rolling, %v", getHookNames(events))
+		}
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		events := make([]hookEvent, 0)
+		id := generateID()
+		buf, _ := json.Marshal(M{
+			CreatedAt: time.Now().Add(-time.Hour).Format(time.RFC3339),
+		})
+		ms.Set(id, buf, 60)
+		sess := newHeaderSession(ms, id, Options{
+			MaxAge:          60,
+			Hooks:           newHooks(&events),
+			AbsoluteTimeout: 60,
+		})
+		sess.Fetch()
+		if !reflect.DeepEqual(getHookNames(events), []string{"expire:" + ReasonAbsoluteTimeout}) ||
+			events[0].id != id {
+			t.Fatalf("the expire hook should be called when the session is timeout")
+		}
+	})
+
+	t.Run("evict", func(t *testing.T) {
+		events := make([]hookEvent, 0)
+		userID := generateID()
+		id := generateID()
+		buf, _ := json.Marshal(M{
+			CreatedAt: time.Now().Format(time.RFC3339),
+		})
+		ms.Set(id, buf, 60)
+		ms.AddUserSession(userID, id, 60)
+		sess := newHeaderSession(ms, "", Options{
+			MaxAge:          60,
+			Hooks:           newHooks(&events),
+			MaxUserSessions: 1,
+		})
+		sess.Fetch()
+		sess.BindUser(userID)
+		if !reflect.DeepEqual(getHookNames(events), []string{"destroy:" + ReasonEvict}) ||
+			events[0].id != id || events[0].data[CreatedAt] == nil {
+			t.Fatalf("the destroy hook should be called when the session is evicted")
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"math"
+)
+
+const (
+	// IDEncodingBase62 encode the session id with [a-zA-Z0-9]
+	IDEncodingBase62 IDEncoding = iota
+	// IDEncodingBase64URL encode the session id with url safe base64(no padding)
+	IDEncodingBase64URL
+	// IDEncodingHex encode the session id with hex
+	IDEncodingHex
+)
+
+const (
+	// defaultIDSize the default entropy size(bytes) of session id
+	defaultIDSize = 18
+)
+
+type (
+	// IDEncoding the encoding of session id
+	IDEncoding int
+)
+
+var letterRunes = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+
+var defaultGenID = NewIDGenerator(defaultIDSize, IDEncodingBase62)
+
+// generateID gen id
+func generateID() string {
+	return defaultGenID()
+}
+
+// randomBytes read random bytes from crypto/rand, panic if fail
+func randomBytes(size int) []byte {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+// base62Length get the length of base62 id which has size bytes entropy
+func base62Length(size int) int {
+	return int(math.Ceil(float64(size*8) / math.Log2(float64(len(letterRunes)))))
+}
+
+// randomBase62 generate random base62 string by rejection sampling
+func randomBase62(length int) string {
+	// the largest multiple of 62 which is not bigger than 256
+	max := byte(256 / len(letterRunes) * len(letterRunes))
+	b := make([]byte, 0, length)
+	for len(b) < length {
+		for _, v := range randomBytes(length - len(b)) {
+			if v >= max {
+				continue
+			}
+			b = append(b, letterRunes[int(v)%len(letterRunes)])
+		}
+	}
+	return string(b)
+}
+
+// idLength get the length of id
+func idLength(size int, encoding IDEncoding) int {
+	switch encoding {
+	case IDEncodingBase64URL:
+		return base64.RawURLEncoding.EncodedLen(size)
+	case IDEncodingHex:
+		return hex.EncodedLen(size)
+	default:
+		return base62Length(size)
+	}
+}
+
+// NewIDGenerator create a session id generator, which uses crypto/rand.
+// The size is the entropy bytes of id, if it's <= 0, the default size(18) will be used.
+func NewIDGenerator(size int, encoding IDEncoding) func() string {
+	if size <= 0 {
+		size = defaultIDSize
+	}
+	switch encoding {
+	case IDEncodingBase64URL:
+		return func() string {
+			return base64.RawURLEncoding.EncodeToString(randomBytes(size))
+		}
+	case IDEncodingHex:
+		return func() string {
+			return hex.EncodeToString(randomBytes(size))
+		}
+	default:
+		length := base62Length(size)
+		return func() string {
+			return randomBase62(length)
+		}
+	}
+}
+
+// isIDChar check the char is valid for the encoding
+func isIDChar(c byte, encoding IDEncoding) bool {
+	switch {
+	case c >= '0' && c <= '9':
+		return true
+	case c >= 'a' && c <= 'f':
+		return true
+	case c >= 'g' && c <= 'z', c >= 'A' && c <= 'Z':
+		return encoding != IDEncodingHex
+	case c == '-', c == '_':
+		return encoding == IDEncodingBase64URL
+	}
+	return false
+}
+
+// NewIDValidator create a session id validator for the id generated by NewIDGenerator
+// with the same size and encoding. The id which has not the same length or
+// has invalid char will be rejected.
+func NewIDValidator(size int, encoding IDEncoding) func(string) bool {
+	if size <= 0 {
+		size = defaultIDSize
+	}
+	length := idLength(size, encoding)
+	return func(id string) bool {
+		if len(id) != length {
+			return false
+		}
+		for i := 0; i < len(id); i++ {
+			if !isIDChar(id[i], encoding) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,56 @@
+package session
+
+import (
+	"testing"
+)
+
+func TestID(t *testing.T) {
+	t.Run("default generate id", func(t *testing.T) {
+		id := generateID()
+		if len(id) != 25 {
+			t.Fatalf("the length of default id should be 25")
+		}
+		if id == generateID() {
+			t.Fatalf("generate id should be random")
+		}
+		if !NewIDValidator(0, IDEncodingBase62)(id) {
+			t.Fatalf("default id should be valid")
+		}
+	})
+
+	t.Run("generate id with encoding", func(t *testing.T) {
+		encodings := map[IDEncoding]int{
+			IDEncodingBase62:    43,
+			IDEncodingBase64URL: 43,
+			IDEncodingHex:       64,
+		}
+		for encoding, length := range encodings {
+			id := NewIDGenerator(32, encoding)()
+			if len(id) != length {
+				t.Fatalf("the length of id(%d) should be %d", encoding, length)
+			}
+			if !NewIDValidator(32, encoding)(id) {
+				t.Fatalf("the id(%d) should be valid", encoding)
+			}
+		}
+	})
+
+	t.Run("validate id", func(t *testing.T) {
+		validate := NewIDValidator(4, IDEncodingHex)
+		if !validate("0a1b2c3d") {
+			t.Fatalf("valid hex id should pass")
+		}
+		if validate("0a1b2c3") || validate("0a1b2c3d4e") {
+			t.Fatalf("id with invalid length should be rejected")
+		}
+		if validate("0a1b2c3g") {
+			t.Fatalf("id with invalid char should be rejected")
+		}
+		if !NewIDValidator(3, IDEncodingBase64URL)("a-_Z") {
+			t.Fatalf("valid base64url id should pass")
+		}
+		if NewIDValidator(3, IDEncodingBase62)("a-_Z0") {
+			t.Fatalf("base62 id should not contain - or _")
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// UserSessionEvictOldest evict the oldest session by created at
+	UserSessionEvictOldest UserSessionPolicy = iota
+	// UserSessionEvictIdle evict the least recently active session by updated at
+	UserSessionEvictIdle
+	// UserSessionReject reject the new session
+	UserSessionReject
+)
+
+type (
+	// UserSessionPolicy the policy when the sessions of user exceed the limit
+	UserSessionPolicy int
+	// UserSessionLimitError the error of rejected session,
+	// it's returned when the sessions of user exceed the limit
+	UserSessionLimitError struct {
+		UserID string
+		Limit  int
+	}
+	// userSession the session of user for eviction
+	userSession struct {
+		key  string
+		at   time.Time
+		data M
+	}
+)
+
+// Error the message of user session limit error
+func (err *UserSessionLimitError) Error() string {
+	return fmt.Sprintf("the sessions of user(%s) exceed the limit(%d)", err.UserID, err.Limit)
+}
+
+// limitUser check the sessions of user, if they exceed the limit,
+// evict the oldest sessions or reject the session by the policy
+func (sess *Session) limitUser(index UserIndex, userID string) (err error) {
+	opts := sess.opts
+	keys, err := index.UserSessions(userID)
+	if err != nil {
+		return
+	}
+	others := make([]userSession, 0, len(keys))
+	for _, key := range keys {
+		if key == sess.cookieValue || key == sess.prevCookieValue {
+			continue
+		}
+		others = append(others, userSession{
+			key: key,
+		})
+	}
+	count := len(others) - opts.MaxUserSessions + 1
+	if count <= 0 {
+		return
+	}
+	if opts.UserSessionPolicy == UserSessionReject {
+		return &UserSessionLimitError{
+			UserID: userID,
+			Limit:  opts.MaxUserSessions,
+		}
+	}
+	for i, item := range others {
+		var buf []byte
+		buf, err = opts.Store.Get(item.key)
+		if err != nil {
+			return
+		}
+		m := make(M)
+		// the invalid session will be evicted first
+		if len(buf) == 0 || sess.unmarshal(buf, &m) != nil {
+			continue
+		}
+		others[i].data = m
+		if opts.UserSessionPolicy == UserSessionEvictIdle {
+			others[i].at, _ = getLastActiveTime(m)
+		} else {
+			others[i].at, _ = getTime(m, CreatedAt)
+		}
+	}
+	sort.SliceStable(others, func(i, j int) bool {
+		return others[i].at.Before(others[j].at)
+	})
+	for _, item := range others[:count] {
+		err = opts.Store.Destroy(item.key)
+		if err != nil {
+			return
+		}
+		err = index.RemoveUserSession(userID, item.key)
+		if err != nil {
+			return
+		}
+		if opts.OnEvict != nil {
+			opts.OnEvict(userID, item.key)
+		}
+		emit(sess.getHooks().OnDestroy, item.key, item.data, ReasonEvict)
+	}
+	return
+}
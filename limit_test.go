@@ -0,0 +1,123 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLimit(t *testing.T) {
+	// create the sessions of user, the first one is the oldest
+	// and the last one is the least recently active
+	createSessions := func(ms *MemoryStore, userID string) []string {
+		now := time.Now()
+		keys := make([]string, 3)
+		for i := range keys {
+			keys[i] = generateID()
+			buf, _ := json.Marshal(M{
+				CreatedAt: now.Add(time.Duration(i-10) * time.Hour).Format(time.RFC3339),
+				UpdatedAt: now.Add(time.Duration(-i) * time.Minute).Format(time.RFC3339),
+			})
+			ms.Set(keys[i], buf, 60)
+			ms.AddUserSession(userID, keys[i], 60)
+		}
+		return keys
+	}
+
+	t.Run("error", func(t *testing.T) {
+		err := &UserSessionLimitError{
+			UserID: "tree.xie",
+			Limit:  3,
+		}
+		if err.Error() != "the sessions of user(tree.xie) exceed the limit(3)" {
+			t.Fatalf("the message of error is wrong")
+		}
+	})
+
+	t.Run("evict oldest", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		userID := generateID()
+		keys := createSessions(ms, userID)
+		evicted := make([]string, 0)
+		sess := newHeaderSession(ms, "", Options{
+			MaxAge:          60,
+			MaxUserSessions: 2,
+			OnEvict: func(userID, id string) {
+				evicted = append(evicted, id)
+			},
+		})
+		sess.Fetch()
+		err := sess.BindUser(userID)
+		if err != nil {
+			t.Fatalf("bind user fail, %v", err)
+		}
+		if !reflect.DeepEqual(evicted, keys[:2]) {
+			t.Fatalf("the oldest sessions should be evicted")
+		}
+		buf, _ := ms.Get(keys[0])
+		if len(buf) != 0 {
+			t.Fatalf("the evicted session should be destroyed")
+		}
+		sess.Commit()
+		result, _ := ms.UserSessions(userID)
+		if len(result) != 2 {
+			t.Fatalf("the sessions of user should not exceed the limit")
+		}
+	})
+
+	t.Run("evict idle", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		userID := generateID()
+		keys := createSessions(ms, userID)
+		evicted := make([]string, 0)
+		sess := newHeaderSession(ms, "", Options{
+			MaxAge:            60,
+			MaxUserSessions:   3,
+			UserSessionPolicy: UserSessionEvictIdle,
+			OnEvict: func(userID, id string) {
+				evicted = append(evicted, id)
+			},
+		})
+		sess.Fetch()
+		err := sess.BindUser(userID)
+		if err != nil {
+			t.Fatalf("bind user fail, %v", err)
+		}
+		if !reflect.DeepEqual(evicted, keys[2:]) {
+			t.Fatalf("the least recently active session should be evicted")
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		userID := generateID()
+		createSessions(ms, userID)
+		sess := newHeaderSession(ms, "", Options{
+			MaxAge:            60,
+			MaxUserSessions:   3,
+			UserSessionPolicy: UserSessionReject,
+		})
+		sess.Fetch()
+		err := sess.BindUser(userID)
+		limitErr, ok := err.(*UserSessionLimitError)
+		if !ok || limitErr.UserID != userID || limitErr.Limit != 3 {
+			t.Fatalf("should return user session limit error")
+		}
+		if sess.GetUserID() != "" {
+			t.Fatalf("the rejected session should not be bound to user")
+		}
+
+		// not exceed the limit
+		sess = newHeaderSession(ms, "", Options{
+			MaxAge:            60,
+			MaxUserSessions:   4,
+			UserSessionPolicy: UserSessionReject,
+		})
+		sess.Fetch()
+		err = sess.BindUser(userID)
+		if err != nil {
+			t.Fatalf("bind user fail, %v", err)
+		}
+	})
+}
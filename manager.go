@@ -0,0 +1,141 @@
+package session
+
+import (
+	"github.com/spf13/cast"
+)
+
+type (
+	// Manager session manager, it manages the sessions across the stores
+	Manager struct {
+		stores []Store
+		// the lifecycle hooks of session, the OnDestroy will be called
+		// when the session is destroyed by manager
+		Hooks *Hooks
+	}
+)
+
+// NewManager create a session manager of the stores
+func NewManager(stores ...Store) *Manager {
+	return &Manager{
+		stores: stores,
+	}
+}
+
+// getData get the session data from store, it will be nil if the session doesn't exist
+func getData(store Store, key string) (data M, err error) {
+	buf, err := store.Get(key)
+	if err != nil || len(buf) == 0 {
+		return
+	}
+	data = make(M)
+	err = decodeData(buf, &data, nil)
+	return
+}
+
+// WatchExpired watch the expired sessions of every store which is an ExpiryNotifier,
+// the expired session will be removed from the index of user(if its data can be got),
+// and the OnExpire hook will be called
+func (m *Manager) WatchExpired() (stop func(), err error) {
+	stops := make([]func(), 0, len(m.stores))
+	stop = func() {
+		for _, fn := range stops {
+			fn()
+		}
+	}
+	for _, store := range m.stores {
+		notifier, ok := store.(ExpiryNotifier)
+		if !ok {
+			continue
+		}
+		var fn func()
+		fn, err = notifier.NotifyExpired(m.newExpiredHandler(store))
+		if err != nil {
+			stop()
+			return
+		}
+		stops = append(stops, fn)
+	}
+	return
+}
+
+// newExpiredHandler create the handler of expired session for the store
+func (m *Manager) newExpiredHandler(store Store) func(string, []byte) {
+	return func(key string, buf []byte) {
+		var data M
+		if len(buf) != 0 {
+			data = make(M)
+			// the data which can't be decoded is ignored
+			if decodeData(buf, &data, nil) != nil {
+				data = nil
+			}
+		}
+		userID := cast.ToString(data[UserID])
+		if index, ok := store.(UserIndex); ok && userID != "" {
+			index.RemoveUserSession(userID, key)
+		}
+		if m.Hooks != nil {
+			emit(m.Hooks.OnExpire, key, data, ReasonExpired)
+		}
+	}
+}
+
+// UserSessions get the sessions of user from every store which is an UserIndex
+func (m *Manager) UserSessions(userID string) (keys []string, err error) {
+	for _, store := range m.stores {
+		index, ok := store.(UserIndex)
+		if !ok {
+			continue
+		}
+		var result []string
+		result, err = index.UserSessions(userID)
+		if err != nil {
+			return
+		}
+		keys = append(keys, result...)
+	}
+	return
+}
+
+// DestroyUserSessions destroy all the sessions of user from every store which is
+// an UserIndex, except the sessions of except(e.g. the current session)
+func (m *Manager) DestroyUserSessions(userID string, except ...string) (err error) {
+	excepted := make(map[string]bool, len(except))
+	for _, key := range except {
+		excepted[key] = true
+	}
+	for _, store := range m.stores {
+		index, ok := store.(UserIndex)
+		if !ok {
+			continue
+		}
+		var keys []string
+		keys, err = index.UserSessions(userID)
+		if err != nil {
+			return
+		}
+		for _, key := range keys {
+			if excepted[key] {
+				continue
+			}
+			var data M
+			if m.Hooks != nil && m.Hooks.OnDestroy != nil {
+				data, err = getData(store, key)
+				if err != nil {
+					return
+				}
+			}
+			err = store.Destroy(key)
+			if err != nil {
+				return
+			}
+			err = index.RemoveUserSession(userID, key)
+			if err != nil {
+				return
+			}
+			if m.Hooks != nil {
+				emit(m.Hooks.OnDestroy, key, data, ReasonDestroyUser)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+func TestManager(t *testing.T) {
+	ms, _ := NewMemoryStore(10)
+	rs := NewRedisStore(nil, &redis.Options{
+		Addr: "localhost:6379",
+	})
+	cs := NewCompressedStore(ms, CompressionGzip, 0)
+	m := NewManager(ms, rs, cs)
+	events := make([]hookEvent, 0)
+	m.Hooks = newHooks(&events)
+	userID := generateID()
+	data := []byte(`{"name":"tree.xie"}`)
+	keys := []string{
+		generateID(),
+		generateID(),
+		generateID(),
+	}
+	ms.Set(keys[0], data, 60)
+	ms.AddUserSession(userID, keys[0], 60)
+	ms.Set(keys[1], data, 60)
+	ms.AddUserSession(userID, keys[1], 60)
+	rs.Set(keys[2], data, 60)
+	rs.AddUserSession(userID, keys[2], 60)
+
+	t.Run("user sessions", func(t *testing.T) {
+		result, err := m.UserSessions(userID)
+		if err != nil || len(result) != 3 {
+			t.Fatalf("get user sessions fail, %v", err)
+		}
+	})
+
+	t.Run("destroy user sessions", func(t *testing.T) {
+		err := m.DestroyUserSessions(userID, keys[0])
+		if err != nil {
+			t.Fatalf("destroy user sessions fail, %v", err)
+		}
+		result, _ := m.UserSessions(userID)
+		if !reflect.DeepEqual(result, []string{keys[0]}) {
+			t.Fatalf("only the excepted session should be kept")
+		}
+		if len(events) != 2 || events[0].reason != ReasonDestroyUser || events[0].data["name"] != "tree.xie" {
+			t.Fatalf("the destroy hook should be called")
+		}
+		for _, key := range keys[1:] {
+			buf, _ := ms.Get(key)
+			if len(buf) != 0 {
+				t.Fatalf("the session should be destroyed")
+			}
+			buf, _ = rs.Get(key)
+			if len(buf) != 0 {
+				t.Fatalf("the session should be destroyed")
+			}
+		}
+	})
+	t.Run("watch expired", func(t *testing.T) {
+		store, _ := NewMemoryStoreWithOptions(&MemoryStoreOptions{
+			Size:            10,
+			JanitorInterval: 10 * time.Millisecond,
+		})
+		defer store.Close()
+		events := make(chan hookEvent, 10)
+		m := NewManager(store, rs)
+		m.Hooks = &Hooks{
+			OnExpire: func(id string, data M, reason string) {
+				events <- hookEvent{
+					id:     id,
+					data:   data,
+					reason: reason,
+				}
+			},
+		}
+		stop, err := m.WatchExpired()
+		if err != nil {
+			t.Fatalf("watch expired fail, %v", err)
+		}
+		defer stop()
+		key := generateID()
+		store.Set(key, []byte(`{"_userID":"`+userID+`"}`), -1)
+		store.AddUserSession(userID, key, 60)
+		select {
+		case e := <-events:
+			if e.id != key || e.reason != ReasonExpired || e.data[UserID] != userID {
+				t.Fatalf("the expire hook should be called with the expired session")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("the expire hook should be called")
+		}
+		store.mutex.Lock()
+		n := len(store.users[userID])
+		store.mutex.Unlock()
+		if n != 0 {
+			t.Fatalf("the expired session should be removed from the index of user")
+		}
+	})
+}
@@ -1,12 +1,21 @@
 package session
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	lru "github.com/hashicorp/golang-lru"
 )
 
+const (
+	defaultJanitorInterval = time.Minute
+)
+
 var (
 	// ErrNotInit error not init
 	ErrNotInit = errors.New("client not init")
@@ -15,34 +24,113 @@ var (
 type (
 	// MemoryStore memory store for session
 	MemoryStore struct {
+		// the counters of stats, they should be 64-bit aligned for atomic operation
+		hits        int64
+		misses      int64
+		expirations int64
+		evictions   int64
+
 		client *lru.Cache
+		// the mutex for compare-and-set
+		mutex sync.Mutex
+		// the sessions of user
+		users map[string]map[string]bool
+		// the interval of janitor which removes and notifies the expired sessions,
+		// default is 1 minute
+		janitorInterval time.Duration
+		// the functions to be called when the session is expired
+		notifiers  map[int]func(string, []byte)
+		notifierID int
+		// the channel to stop janitor
+		janitorDone chan struct{}
+		// the janitor keeps sweeping the expired sessions until close
+		sweeping bool
+		// the session is removed explicitly, not evicted
+		removing bool
+		// the sessions evicted because of the capacity
+		evicted []*memoryStoreEvicted
+		onEvict func(string, []byte)
+	}
+	// MemoryStoreOptions memory store options
+	MemoryStoreOptions struct {
+		// the max count of sessions
+		Size int
+		// the interval of janitor which removes and notifies the expired sessions
+		// while they are notified(NotifyExpired), default is 1 minute
+		JanitorInterval time.Duration
+		// keep the janitor sweeping the expired sessions until close, otherwise
+		// the janitor only runs while the expired sessions are notified
+		Sweep bool
+		// function to be called when the session is evicted because of the capacity
+		OnEvict func(key string, data []byte)
+	}
+	// MemoryStoreStats memory store stats
+	MemoryStoreStats struct {
+		Hits        int64
+		Misses      int64
+		Expirations int64
+		Evictions   int64
+		Size        int
+	}
+	// memoryStoreEvicted the session evicted because of the capacity
+	memoryStoreEvicted struct {
+		Key  string
+		Data []byte
 	}
 	// MemoryStoreInfo memory store info
 	MemoryStoreInfo struct {
 		ExpiredAt int64
 		Data      []byte
+		Version   int64
 	}
 )
 
+// getInfo get the not expired info of session
+func (ms *MemoryStore) getInfo(key string) *MemoryStoreInfo {
+	v, found := ms.client.Get(key)
+	if !found {
+		return nil
+	}
+	info, ok := v.(*MemoryStoreInfo)
+	if !ok {
+		return nil
+	}
+	if info.ExpiredAt < time.Now().Unix() {
+		return nil
+	}
+	return info
+}
+
+// getVersion get the version of session, 0 means not exists
+func (ms *MemoryStore) getVersion(key string) int64 {
+	info := ms.getInfo(key)
+	if info == nil {
+		return 0
+	}
+	return info.Version
+}
+
 // Get get the seesion from memory
 func (ms *MemoryStore) Get(key string) (data []byte, err error) {
+	data, _, err = ms.GetVersion(key)
+	return
+}
+
+// GetVersion get the session and its version from memory
+func (ms *MemoryStore) GetVersion(key string) (data []byte, version int64, err error) {
 	client := ms.client
 	if client == nil {
 		err = ErrNotInit
 		return
 	}
-	v, found := client.Get(key)
-	if !found {
-		return
-	}
-	info, ok := v.(*MemoryStoreInfo)
-	if !ok {
-		return
-	}
-	if info.ExpiredAt < time.Now().Unix() {
+	info := ms.getInfo(key)
+	if info == nil {
+		atomic.AddInt64(&ms.misses, 1)
 		return
 	}
+	atomic.AddInt64(&ms.hits, 1)
 	data = info.Data
+	version = info.Version
 	return
 }
 
@@ -53,15 +141,339 @@ func (ms *MemoryStore) Set(key string, data []byte, ttl int) (err error) {
 		err = ErrNotInit
 		return
 	}
+	ms.mutex.Lock()
+	defer ms.unlock()
+	ms.add(key, data, ttl, ms.getVersion(key)+1)
+	return
+}
+
+// SetVersion set the session to memory if the stored version is equal to the version
+func (ms *MemoryStore) SetVersion(key string, data []byte, ttl int, version int64) (err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	ms.mutex.Lock()
+	defer ms.unlock()
+	if ms.getVersion(key) != version {
+		err = ErrConflict
+		return
+	}
+	ms.add(key, data, ttl, version+1)
+	return
+}
+
+func (ms *MemoryStore) add(key string, data []byte, ttl int, version int64) {
 	expiredAt := time.Now().Unix() + int64(ttl)
 	info := &MemoryStoreInfo{
 		ExpiredAt: expiredAt,
 		Data:      data,
+		Version:   version,
+	}
+	ms.client.Add(key, info)
+}
+
+// Touch update the ttl of the session
+func (ms *MemoryStore) Touch(key string, ttl int) (err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	ms.mutex.Lock()
+	defer ms.unlock()
+	info := ms.getInfo(key)
+	if info == nil {
+		return
+	}
+	ms.add(key, info.Data, ttl, info.Version)
+	return
+}
+
+// TTL get the remaining ttl(seconds) of the session, it's -2 if the session doesn't exist
+func (ms *MemoryStore) TTL(key string) (ttl int, err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	v, found := client.Peek(key)
+	info, ok := v.(*MemoryStoreInfo)
+	if !found || !ok {
+		ttl = -2
+		return
+	}
+	ttl = int(info.ExpiredAt - time.Now().Unix())
+	if ttl < 0 {
+		ttl = -2
+	}
+	return
+}
+
+// update modify the field of session data atomically, the session data
+// will be encoded by the codec which it was encoded with
+func (ms *MemoryStore) update(key, field string, ttl int, fn func(interface{}) (interface{}, error)) (err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	ms.mutex.Lock()
+	defer ms.unlock()
+	info := ms.getInfo(key)
+	if info == nil || len(info.Data) == 0 {
+		err = ErrNotExists
+		return
+	}
+	m := make(M)
+	err = decodeData(info.Data, &m, nil)
+	if err != nil {
+		return
+	}
+	value, err := fn(m[field])
+	if err != nil {
+		return
+	}
+	m[field] = value
+	var buf []byte
+	if codec := getCodec(info.Data[0]); codec != nil {
+		buf, err = encodeData(codec, m)
+	} else {
+		buf, err = json.Marshal(m)
+	}
+	if err != nil {
+		return
+	}
+	ms.add(key, buf, ttl, info.Version+1)
+	return
+}
+
+// Incr increase the field of session data by delta atomically
+func (ms *MemoryStore) Incr(key, field string, delta int64, ttl int) (value int64, err error) {
+	err = ms.update(key, field, ttl, func(v interface{}) (interface{}, error) {
+		var e error
+		value, e = incrValue(v, delta)
+		return value, e
+	})
+	return
+}
+
+// Push prepend the value to the list field of session data atomically
+func (ms *MemoryStore) Push(key, field string, value interface{}, maxLen int, ttl int) (values []interface{}, err error) {
+	err = ms.update(key, field, ttl, func(v interface{}) (interface{}, error) {
+		values = pushValue(v, value, maxLen)
+		return values, nil
+	})
+	return
+}
+
+// AddToSet add the value to the set field of session data atomically
+func (ms *MemoryStore) AddToSet(key, field string, value interface{}, ttl int) (values []interface{}, err error) {
+	err = ms.update(key, field, ttl, func(v interface{}) (interface{}, error) {
+		values = addToSetValue(v, value)
+		return values, nil
+	})
+	return
+}
+
+// AddUserSession add the session to the index of user, the session will be
+// removed from the index when it's expired
+func (ms *MemoryStore) AddUserSession(userID, key string, ttl int) (err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if ms.users == nil {
+		ms.users = make(map[string]map[string]bool)
+	}
+	sessions := ms.users[userID]
+	if sessions == nil {
+		sessions = make(map[string]bool)
+		ms.users[userID] = sessions
+	}
+	sessions[key] = true
+	return
+}
+
+// RemoveUserSession remove the session from the index of user
+func (ms *MemoryStore) RemoveUserSession(userID, key string) (err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	sessions := ms.users[userID]
+	delete(sessions, key)
+	if len(sessions) == 0 {
+		delete(ms.users, userID)
 	}
-	client.Add(key, info)
 	return
 }
 
+// UserSessions get the sessions of user, the expired or removed sessions
+// will be pruned from the index
+func (ms *MemoryStore) UserSessions(userID string) (keys []string, err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	sessions := ms.users[userID]
+	now := time.Now().Unix()
+	for key := range sessions {
+		v, found := client.Peek(key)
+		if !found || v.(*MemoryStoreInfo).ExpiredAt < now {
+			delete(sessions, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(sessions) == 0 {
+		delete(ms.users, userID)
+	}
+	sort.Strings(keys)
+	return
+}
+
+// unlock unlock the store, and call the OnEvict with the evicted sessions
+func (ms *MemoryStore) unlock() {
+	evicted := ms.evicted
+	ms.evicted = nil
+	ms.mutex.Unlock()
+	for _, item := range evicted {
+		ms.onEvict(item.Key, item.Data)
+	}
+}
+
+// evict the callback of lru eviction, it's called with the lock
+func (ms *MemoryStore) evict(key, value interface{}) {
+	if ms.removing {
+		return
+	}
+	atomic.AddInt64(&ms.evictions, 1)
+	info, ok := value.(*MemoryStoreInfo)
+	if !ok || ms.onEvict == nil {
+		return
+	}
+	ms.evicted = append(ms.evicted, &memoryStoreEvicted{
+		Key:  key.(string),
+		Data: info.Data,
+	})
+}
+
+// remove remove the session explicitly, it should be called with the lock
+func (ms *MemoryStore) remove(key interface{}) {
+	ms.removing = true
+	ms.client.Remove(key)
+	ms.removing = false
+}
+
+// startJanitor start the janitor if it isn't running, it should be called with the lock
+func (ms *MemoryStore) startJanitor() {
+	if ms.janitorDone != nil {
+		return
+	}
+	interval := ms.janitorInterval
+	if interval <= 0 {
+		interval = defaultJanitorInterval
+	}
+	ms.janitorDone = make(chan struct{})
+	go ms.runJanitor(interval, ms.janitorDone)
+}
+
+// stopJanitor stop the janitor if it is running, it should be called with the lock
+func (ms *MemoryStore) stopJanitor() {
+	if ms.janitorDone == nil {
+		return
+	}
+	close(ms.janitorDone)
+	ms.janitorDone = nil
+}
+
+// NotifyExpired call the function with the session id and data when the session
+// is expired, the janitor will be started to remove the expired sessions
+// until all the notifications are stopped
+func (ms *MemoryStore) NotifyExpired(fn func(key string, data []byte)) (stop func(), err error) {
+	client := ms.client
+	if client == nil {
+		err = ErrNotInit
+		return
+	}
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	if ms.notifiers == nil {
+		ms.notifiers = make(map[int]func(string, []byte))
+	}
+	ms.notifierID++
+	id := ms.notifierID
+	ms.notifiers[id] = fn
+	ms.startJanitor()
+	once := sync.Once{}
+	stop = func() {
+		once.Do(func() {
+			ms.mutex.Lock()
+			defer ms.mutex.Unlock()
+			delete(ms.notifiers, id)
+			if len(ms.notifiers) == 0 && !ms.sweeping {
+				ms.stopJanitor()
+			}
+		})
+	}
+	return
+}
+
+// runJanitor remove the expired sessions periodically until done
+func (ms *MemoryStore) runJanitor(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ms.removeExpired()
+		}
+	}
+}
+
+// removeExpired remove the expired sessions and notify them
+func (ms *MemoryStore) removeExpired() {
+	ms.mutex.Lock()
+	now := time.Now().Unix()
+	client := ms.client
+	expired := make(map[string][]byte)
+	for _, k := range client.Keys() {
+		v, found := client.Peek(k)
+		if !found {
+			continue
+		}
+		info, ok := v.(*MemoryStoreInfo)
+		if ok && info.ExpiredAt < now {
+			ms.remove(k)
+			expired[k.(string)] = info.Data
+		}
+	}
+	atomic.AddInt64(&ms.expirations, int64(len(expired)))
+	notifiers := make([]func(string, []byte), 0, len(ms.notifiers))
+	for _, fn := range ms.notifiers {
+		notifiers = append(notifiers, fn)
+	}
+	ms.mutex.Unlock()
+	for key, data := range expired {
+		for _, fn := range notifiers {
+			fn(key, data)
+		}
+	}
+}
+
 // Destroy remove the session from memory
 func (ms *MemoryStore) Destroy(key string) (err error) {
 	client := ms.client
@@ -69,18 +481,86 @@ func (ms *MemoryStore) Destroy(key string) (err error) {
 		err = ErrNotInit
 		return
 	}
-	client.Remove(key)
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.remove(key)
+	return
+}
+
+// Stats get the stats of memory store
+func (ms *MemoryStore) Stats() (stats MemoryStoreStats) {
+	stats.Hits = atomic.LoadInt64(&ms.hits)
+	stats.Misses = atomic.LoadInt64(&ms.misses)
+	stats.Expirations = atomic.LoadInt64(&ms.expirations)
+	stats.Evictions = atomic.LoadInt64(&ms.evictions)
+	if ms.client != nil {
+		stats.Size = ms.client.Len()
+	}
 	return
 }
 
+// Close stop the sweeper and the expiration notifications
+func (ms *MemoryStore) Close() error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.sweeping = false
+	ms.notifiers = nil
+	ms.stopJanitor()
+	return nil
+}
+
+// GetContext get the session from memory with context
+func (ms *MemoryStore) GetContext(ctx context.Context, key string) (data []byte, err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+	return ms.Get(key)
+}
+
+// SetContext set the session to memory with context
+func (ms *MemoryStore) SetContext(ctx context.Context, key string, data []byte, ttl int) (err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+	return ms.Set(key, data, ttl)
+}
+
+// DestroyContext remove the session from memory with context
+func (ms *MemoryStore) DestroyContext(ctx context.Context, key string) (err error) {
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+	return ms.Destroy(key)
+}
+
 // NewMemoryStore create new memory store instance
 func NewMemoryStore(size int) (store *MemoryStore, err error) {
-	client, err := lru.New(size)
+	return NewMemoryStoreWithOptions(&MemoryStoreOptions{
+		Size: size,
+	})
+}
+
+// NewMemoryStoreWithOptions create new memory store instance with options,
+// if sweep is enabled, Close should be called to stop the sweeper
+func NewMemoryStoreWithOptions(opts *MemoryStoreOptions) (store *MemoryStore, err error) {
+	store = &MemoryStore{
+		janitorInterval: opts.JanitorInterval,
+		onEvict:         opts.OnEvict,
+	}
+	client, err := lru.NewWithEvict(opts.Size, store.evict)
 	if err != nil {
+		store = nil
 		return
 	}
-	store = &MemoryStore{
-		client: client,
+	store.client = client
+	if opts.Sweep {
+		store.mutex.Lock()
+		store.sweeping = true
+		store.startJanitor()
+		store.mutex.Unlock()
 	}
 	return
 }
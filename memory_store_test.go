@@ -2,7 +2,9 @@ package session
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 )
 
 func TestMemoryStore(t *testing.T) {
@@ -72,4 +74,227 @@ func TestMemoryStore(t *testing.T) {
 			t.Fatalf("expired data should be nil")
 		}
 	})
+	t.Run("context", func(t *testing.T) {
+		ctx := context.Background()
+		err := ms.SetContext(ctx, key, data, ttl)
+		if err != nil {
+			t.Fatalf("set data with context fail, %v", err)
+		}
+		buf, err := ms.GetContext(ctx, key)
+		if err != nil || !bytes.Equal(data, buf) {
+			t.Fatalf("get data with context fail, %v", err)
+		}
+		err = ms.DestroyContext(ctx, key)
+		if err != nil {
+			t.Fatalf("destroy data with context fail, %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = ms.GetContext(ctx, key)
+		if err != context.Canceled {
+			t.Fatalf("canceled context should return error")
+		}
+	})
+	t.Run("touch", func(t *testing.T) {
+		err := ms.Touch(key, ttl)
+		if err != nil {
+			t.Fatalf("touch not exists data fail, %v", err)
+		}
+		ms.Set(key, data, 10)
+		err = ms.Touch(key, ttl)
+		if err != nil {
+			t.Fatalf("touch data fail, %v", err)
+		}
+		v, _ := ms.client.Get(key)
+		if v.(*MemoryStoreInfo).ExpiredAt < time.Now().Unix()+int64(ttl)-1 {
+			t.Fatalf("the ttl should be updated after touch")
+		}
+		n, err := ms.TTL(key)
+		if err != nil || n < ttl-1 {
+			t.Fatalf("get ttl fail, %v", err)
+		}
+		n, _ = ms.TTL(generateID())
+		if n != -2 {
+			t.Fatalf("the ttl of not exists session should be -2")
+		}
+		_, err = (&MemoryStore{}).TTL(key)
+		if err != ErrNotInit {
+			t.Fatalf("should return not init error")
+		}
+		err = (&MemoryStore{}).Touch(key, ttl)
+		if err != ErrNotInit {
+			t.Fatalf("should return not init error")
+		}
+	})
+	t.Run("version", func(t *testing.T) {
+		key := generateID()
+		_, version, err := ms.GetVersion(key)
+		if err != nil || version != 0 {
+			t.Fatalf("the version of not exists data should be 0")
+		}
+		err = ms.SetVersion(key, data, ttl, 0)
+		if err != nil {
+			t.Fatalf("set data with version fail, %v", err)
+		}
+		err = ms.SetVersion(key, data, ttl, 0)
+		if err != ErrConflict {
+			t.Fatalf("set data with stale version should return conflict error")
+		}
+		ms.Touch(key, ttl)
+		buf, version, err := ms.GetVersion(key)
+		if err != nil || version != 1 || !bytes.Equal(data, buf) {
+			t.Fatalf("get data with version fail, %v", err)
+		}
+		ms.Set(key, data, ttl)
+		_, version, _ = ms.GetVersion(key)
+		if version != 2 {
+			t.Fatalf("set data should increase the version")
+		}
+		err = (&MemoryStore{}).SetVersion(key, data, ttl, 0)
+		if err != ErrNotInit {
+			t.Fatalf("should return not init error")
+		}
+	})
+	t.Run("user index", func(t *testing.T) {
+		tmp := &MemoryStore{}
+		err := tmp.AddUserSession("tree.xie", key, ttl)
+		if err != ErrNotInit {
+			t.Fatalf("should return not init error")
+		}
+		err = tmp.RemoveUserSession("tree.xie", key)
+		if err != ErrNotInit {
+			t.Fatalf("should return not init error")
+		}
+		_, err = tmp.UserSessions("tree.xie")
+		if err != ErrNotInit {
+			t.Fatalf("should return not init error")
+		}
+
+		ms.Set(key, data, -100)
+		ms.AddUserSession("tree.xie", key, ttl)
+		keys, err := ms.UserSessions("tree.xie")
+		if err != nil || len(keys) != 0 {
+			t.Fatalf("the expired session should be pruned")
+		}
+		if len(ms.users) != 0 {
+			t.Fatalf("the empty index should be removed")
+		}
+	})
+	t.Run("notify expired", func(t *testing.T) {
+		_, err := (&MemoryStore{}).NotifyExpired(func(string, []byte) {})
+		if err != ErrNotInit {
+			t.Fatalf("should return not init error")
+		}
+
+		store, _ := NewMemoryStoreWithOptions(&MemoryStoreOptions{
+			Size:            10,
+			JanitorInterval: 10 * time.Millisecond,
+		})
+		expired := make(chan string, 10)
+		stop, err := store.NotifyExpired(func(key string, buf []byte) {
+			if !bytes.Equal(data, buf) {
+				t.Errorf("the data of expired session is wrong")
+			}
+			expired <- key
+		})
+		if err != nil {
+			t.Fatalf("notify expired fail, %v", err)
+		}
+		store.Set("a", data, ttl)
+		store.Set("b", data, -1)
+		select {
+		case key := <-expired:
+			if key != "b" {
+				t.Fatalf("only the expired session should be notified")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("the expired session should be notified")
+		}
+		if store.client.Contains("b") || !store.client.Contains("a") {
+			t.Fatalf("the expired session should be removed by janitor")
+		}
+		stop()
+		stop()
+		store.mutex.Lock()
+		done := store.janitorDone
+		store.mutex.Unlock()
+		if done != nil {
+			t.Fatalf("the janitor should be stopped when all notifications are stopped")
+		}
+	})
+	t.Run("sweeper", func(t *testing.T) {
+		_, err := NewMemoryStoreWithOptions(&MemoryStoreOptions{})
+		if err == nil {
+			t.Fatalf("invalid size should return error")
+		}
+		store, err := NewMemoryStoreWithOptions(&MemoryStoreOptions{
+			Size:            10,
+			JanitorInterval: 10 * time.Millisecond,
+			Sweep:           true,
+		})
+		if err != nil {
+			t.Fatalf("new memory store with options fail, %v", err)
+		}
+		store.Set("a", data, ttl)
+		store.Set("b", data, -1)
+		for i := 0; i < 100 && store.Stats().Expirations == 0; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		stats := store.Stats()
+		if stats.Expirations != 1 || stats.Size != 1 || stats.Evictions != 0 {
+			t.Fatalf("the expired session should be removed by sweeper")
+		}
+		// the sweeper keeps running after the notification is stopped
+		stop, _ := store.NotifyExpired(func(string, []byte) {})
+		stop()
+		store.mutex.Lock()
+		running := store.janitorDone != nil
+		store.mutex.Unlock()
+		if !running {
+			t.Fatalf("the sweeper should keep running until close")
+		}
+		err = store.Close()
+		if err != nil {
+			t.Fatalf("close memory store fail, %v", err)
+		}
+		store.mutex.Lock()
+		running = store.janitorDone != nil
+		store.mutex.Unlock()
+		if running {
+			t.Fatalf("the sweeper should be stopped after close")
+		}
+	})
+
+	t.Run("evict and stats", func(t *testing.T) {
+		evicted := make([]string, 0)
+		var store *MemoryStore
+		store, _ = NewMemoryStoreWithOptions(&MemoryStoreOptions{
+			Size: 2,
+			OnEvict: func(key string, buf []byte) {
+				if !bytes.Equal(data, buf) {
+					t.Errorf("the data of evicted session is wrong")
+				}
+				// the store can be used in callback
+				store.Get(key)
+				evicted = append(evicted, key)
+			},
+		})
+		store.Set("a", data, ttl)
+		store.Set("b", data, ttl)
+		store.Get("a")
+		store.Get("c")
+		store.Set("c", data, ttl)
+		store.Destroy("c")
+		if len(evicted) != 1 || evicted[0] != "b" {
+			t.Fatalf("only the session evicted because of the capacity should be notified")
+		}
+		stats := store.Stats()
+		if stats.Hits != 1 || stats.Misses != 2 || stats.Evictions != 1 || stats.Size != 1 {
+			t.Fatalf("the stats of memory store is wrong, %v", stats)
+		}
+		if (&MemoryStore{}).Stats().Size != 0 {
+			t.Fatalf("the size of not init store should be 0")
+		}
+	})
 }
@@ -0,0 +1,336 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	// the key of field value which is wrapped as a map when the codec is set
+	hashFieldValue = "v"
+)
+
+var (
+	// incrScript increase the field of hash and update its ttl if the hash exists,
+	// nil will be returned if the hash doesn't exist
+	incrScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return false
+end
+local value = redis.call("HINCRBY", KEYS[1], ARGV[1], ARGV[2])
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call("EXPIRE", KEYS[1], ttl)
+else
+	redis.call("PERSIST", KEYS[1])
+end
+return value
+`)
+)
+
+type (
+	// RedisHashStore redis hash store for session, each session is saved as a hash,
+	// the key of session data(including the metadata such as _createdAt) is saved
+	// as a field of hash, so it can be inspected by HGET.
+	RedisHashStore struct {
+		client *redis.Client
+		// the codec to encode the field value(wrapped as a map), it should be
+		// registered. If it's not set, the value will be encoded by json
+		// without version byte
+		Codec Codec
+		// the prefix of session key in redis, only the expired keys with the prefix
+		// are notified, so the database can be shared with other keys
+		Prefix string
+	}
+)
+
+// encode encode the field value or session data
+func (rhs *RedisHashStore) encode(v interface{}) ([]byte, error) {
+	if rhs.Codec != nil {
+		return encodeData(rhs.Codec, v)
+	}
+	return json.Marshal(v)
+}
+
+// expireHash set the ttl of hash, the hash will be persisted if ttl <= 0,
+// the same as the session which is set without ttl
+func expireHash(pipe redis.Pipeliner, key string, ttl int) {
+	if ttl <= 0 {
+		pipe.Persist(key)
+		return
+	}
+	pipe.Expire(key, time.Duration(int64(time.Second)*int64(ttl)))
+}
+
+// encodeField encode the field value, the value is wrapped as a map if the codec
+// is set, so it can be decoded as interface by any codec(e.g. gob)
+func (rhs *RedisHashStore) encodeField(v interface{}) ([]byte, error) {
+	if rhs.Codec != nil {
+		// the field is decoded by the registered codec
+		if getCodec(rhs.Codec.ID()) == nil {
+			return nil, ErrCodecNotRegistered
+		}
+		return encodeData(rhs.Codec, M{
+			hashFieldValue: v,
+		})
+	}
+	return json.Marshal(v)
+}
+
+// decodeField decode the field value
+func (rhs *RedisHashStore) decodeField(buf []byte) (value interface{}, err error) {
+	if len(buf) == 0 || getCodec(buf[0]) == nil {
+		err = json.Unmarshal(buf, &value)
+		return
+	}
+	m := make(M)
+	err = decodeData(buf, &m, nil)
+	if err != nil {
+		return
+	}
+	value = m[hashFieldValue]
+	return
+}
+
+// getFields encode the session data to the fields of hash
+func (rhs *RedisHashStore) getFields(m M) (fields map[string]interface{}, err error) {
+	fields = make(map[string]interface{}, len(m))
+	for k, v := range m {
+		var buf []byte
+		buf, err = rhs.encodeField(v)
+		if err != nil {
+			return
+		}
+		fields[k] = buf
+	}
+	return
+}
+
+// Get get the session from redis hash, the session data is reconstructed
+// from the fields of hash
+func (rhs *RedisHashStore) Get(key string) (data []byte, err error) {
+	key = rhs.Prefix + key
+	result, err := rhs.client.HGetAll(key).Result()
+	if err != nil || len(result) == 0 {
+		return
+	}
+	m := make(M, len(result))
+	for k, v := range result {
+		m[k], err = rhs.decodeField([]byte(v))
+		if err != nil {
+			return
+		}
+	}
+	return rhs.encode(m)
+}
+
+// GetContext get the session from redis hash with context
+func (rhs *RedisHashStore) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return getContext(ctx, key, rhs.Get)
+}
+
+// Set set the session to redis hash, the previous fields will be removed
+func (rhs *RedisHashStore) Set(key string, data []byte, ttl int) (err error) {
+	key = rhs.Prefix + key
+	m := make(M)
+	err = decodeData(data, &m, nil)
+	if err != nil {
+		return
+	}
+	fields, err := rhs.getFields(m)
+	if err != nil {
+		return
+	}
+	_, err = rhs.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(key)
+		if len(fields) != 0 {
+			pipe.HMSet(key, fields)
+			expireHash(pipe, key, ttl)
+		}
+		return nil
+	})
+	return
+}
+
+// SetContext set the session to redis hash with context
+func (rhs *RedisHashStore) SetContext(ctx context.Context, key string, data []byte, ttl int) error {
+	return runContext(ctx, func() error {
+		return rhs.Set(key, data, ttl)
+	})
+}
+
+// SetFields set the changed fields and remove the deleted fields of session,
+// the ttl of session will be updated too
+func (rhs *RedisHashStore) SetFields(key string, fields M, deleted []string, ttl int) (err error) {
+	key = rhs.Prefix + key
+	values, err := rhs.getFields(fields)
+	if err != nil {
+		return
+	}
+	_, err = rhs.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		if len(values) != 0 {
+			pipe.HMSet(key, values)
+		}
+		if len(deleted) != 0 {
+			pipe.HDel(key, deleted...)
+		}
+		expireHash(pipe, key, ttl)
+		return nil
+	})
+	return
+}
+
+// update modify the field of session data atomically by WATCH/MULTI,
+// it is retried until the field isn't modified by others during update
+func (rhs *RedisHashStore) update(key, field string, ttl int, fn func(interface{}) (interface{}, error)) (err error) {
+	for {
+		err = rhs.client.Watch(func(tx *redis.Tx) error {
+			buf, err := tx.HGet(key, field).Bytes()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+			// the field is not exists, check the session
+			if err == redis.Nil {
+				n, err := tx.Exists(key).Result()
+				if err != nil {
+					return err
+				}
+				if n == 0 {
+					return ErrNotExists
+				}
+			}
+			var value interface{}
+			if len(buf) != 0 {
+				value, err = rhs.decodeField(buf)
+				if err != nil {
+					return err
+				}
+			}
+			value, err = fn(value)
+			if err != nil {
+				return err
+			}
+			data, err := rhs.encodeField(value)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+				pipe.HSet(key, field, data)
+				expireHash(pipe, key, ttl)
+				return nil
+			})
+			return err
+		}, key)
+		if err != redis.TxFailedErr {
+			return
+		}
+	}
+}
+
+// Incr increase the field of session data by delta atomically, it uses HINCRBY
+// in lua script if the codec isn't set, otherwise the field is modified by WATCH/MULTI
+func (rhs *RedisHashStore) Incr(key, field string, delta int64, ttl int) (value int64, err error) {
+	key = rhs.Prefix + key
+	if rhs.Codec != nil {
+		err = rhs.update(key, field, ttl, func(v interface{}) (interface{}, error) {
+			var e error
+			value, e = incrValue(v, delta)
+			return value, e
+		})
+		return
+	}
+	value, err = incrScript.Run(rhs.client, []string{key}, field, delta, ttl).Int64()
+	if err == redis.Nil {
+		err = ErrNotExists
+	}
+	return
+}
+
+// Push prepend the value to the list field of session data atomically
+func (rhs *RedisHashStore) Push(key, field string, value interface{}, maxLen int, ttl int) (values []interface{}, err error) {
+	key = rhs.Prefix + key
+	err = rhs.update(key, field, ttl, func(v interface{}) (interface{}, error) {
+		values = pushValue(v, value, maxLen)
+		return values, nil
+	})
+	return
+}
+
+// AddToSet add the value to the set field of session data atomically
+func (rhs *RedisHashStore) AddToSet(key, field string, value interface{}, ttl int) (values []interface{}, err error) {
+	key = rhs.Prefix + key
+	err = rhs.update(key, field, ttl, func(v interface{}) (interface{}, error) {
+		values = addToSetValue(v, value)
+		return values, nil
+	})
+	return
+}
+
+// TTL get the remaining ttl(seconds) of the session
+func (rhs *RedisHashStore) TTL(key string) (int, error) {
+	return getTTL(rhs.client, rhs.Prefix+key)
+}
+
+// Touch update the ttl of the session, the session without ttl(ttl <= 0) isn't changed
+func (rhs *RedisHashStore) Touch(key string, ttl int) error {
+	key = rhs.Prefix + key
+	if ttl <= 0 {
+		return nil
+	}
+	expiration := time.Duration(int64(time.Second) * int64(ttl))
+	return rhs.client.Expire(key, expiration).Err()
+}
+
+// AddUserSession add the session to the index of user
+func (rhs *RedisHashStore) AddUserSession(userID, key string, ttl int) error {
+	return addUserSession(rhs.client, rhs.Prefix, userID, key, ttl)
+}
+
+// RemoveUserSession remove the session from the index of user
+func (rhs *RedisHashStore) RemoveUserSession(userID, key string) error {
+	return removeUserSession(rhs.client, rhs.Prefix, userID, key)
+}
+
+// UserSessions get the sessions of user
+func (rhs *RedisHashStore) UserSessions(userID string) ([]string, error) {
+	return userSessions(rhs.client, rhs.Prefix, userID)
+}
+
+// NotifyExpired call the function with the session id when the session is expired,
+// the keyspace notification of redis should be enabled(notify-keyspace-events Ex).
+// All the expired keys of database are notified if the prefix isn't set, so a
+// dedicated database should be used. The data of expired session can't be got,
+// so it's always nil.
+func (rhs *RedisHashStore) NotifyExpired(fn func(key string, data []byte)) (stop func(), err error) {
+	return notifyExpired(rhs.client, rhs.Prefix, fn)
+}
+
+// Destroy remove the session from redis
+func (rhs *RedisHashStore) Destroy(key string) error {
+	return rhs.client.Del(rhs.Prefix + key).Err()
+}
+
+// DestroyContext remove the session from redis with context
+func (rhs *RedisHashStore) DestroyContext(ctx context.Context, key string) error {
+	return runContext(ctx, func() error {
+		return rhs.Destroy(key)
+	})
+}
+
+// NewRedisHashStore create new redis hash store instance
+func NewRedisHashStore(client *redis.Client, opts *redis.Options) *RedisHashStore {
+	if client == nil && opts == nil {
+		panic(errors.New("client and opts can both be nil"))
+	}
+	rhs := &RedisHashStore{}
+	if client != nil {
+		rhs.client = client
+	} else {
+		rhs.client = redis.NewClient(opts)
+	}
+	return rhs
+}
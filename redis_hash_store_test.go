@@ -0,0 +1,344 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/spf13/cast"
+)
+
+func TestRedisHashStore(t *testing.T) {
+	key := generateID()
+	ttl := 300
+	data, _ := json.Marshal(M{
+		"name": "tree.xie",
+		"age":  18,
+	})
+	rhs := NewRedisHashStore(nil, &redis.Options{
+		Addr: "localhost:6379",
+	})
+	t.Run("new redis hash store", func(t *testing.T) {
+		client := redis.NewClient(&redis.Options{
+			Addr: "localhost:6379",
+		})
+		NewRedisHashStore(client, nil)
+	})
+
+	t.Run("get not exists data", func(t *testing.T) {
+		buf, err := rhs.Get(key)
+		if err != nil || len(buf) != 0 {
+			t.Fatalf("shoud return empty bytes")
+		}
+	})
+
+	t.Run("set data", func(t *testing.T) {
+		err := rhs.Set(key, data, ttl)
+		if err != nil {
+			t.Fatalf("set data fail, %v", err)
+		}
+		name, _ := rhs.client.HGet(key, "name").Result()
+		if name != `"tree.xie"` {
+			t.Fatalf("the key of session should be saved as the field of hash")
+		}
+		d, _ := rhs.client.TTL(key).Result()
+		if d < 10*time.Second {
+			t.Fatalf("the ttl of hash should be set")
+		}
+		buf, err := rhs.Get(key)
+		if err != nil {
+			t.Fatalf("get data fail after set, %v", err)
+		}
+		m := make(M)
+		json.Unmarshal(buf, &m)
+		if m["name"] != "tree.xie" || m["age"].(float64) != 18 {
+			t.Fatalf("the data is not the same after set")
+		}
+
+		// the previous fields should be removed
+		buf, _ = json.Marshal(M{
+			"name": "vicanso",
+		})
+		rhs.Set(key, buf, ttl)
+		n, _ := rhs.client.HLen(key).Result()
+		if n != 1 {
+			t.Fatalf("the previous fields should be removed after set")
+		}
+	})
+
+	t.Run("set fields", func(t *testing.T) {
+		err := rhs.SetFields(key, M{
+			"age": 20,
+		}, []string{
+			"name",
+		}, ttl)
+		if err != nil {
+			t.Fatalf("set fields fail, %v", err)
+		}
+		result, _ := rhs.client.HGetAll(key).Result()
+		if len(result) != 1 || result["age"] != "20" {
+			t.Fatalf("set fields should update the changed fields and remove the deleted fields")
+		}
+	})
+
+	t.Run("codec", func(t *testing.T) {
+		store := NewRedisHashStore(rhs.client, nil)
+		store.Codec = &BinaryCodec{}
+		now := time.Unix(time.Now().Unix(), 0)
+		buf, _ := encodeData(store.Codec, M{
+			"count":   int64(1),
+			"loginAt": now,
+		})
+		id := generateID()
+		err := store.Set(id, buf, ttl)
+		if err != nil {
+			t.Fatalf("set data with codec fail, %v", err)
+		}
+		buf, err = store.Get(id)
+		if err != nil {
+			t.Fatalf("get data with codec fail, %v", err)
+		}
+		m := make(M)
+		decodeData(buf, &m, nil)
+		if m["count"] != int64(1) || !now.Equal(m["loginAt"].(time.Time)) {
+			t.Fatalf("the type of value should be preserved by codec")
+		}
+		store.Destroy(id)
+	})
+
+	t.Run("round trip of codecs", func(t *testing.T) {
+		for _, codec := range []Codec{
+			nil,
+			&JSONCodec{},
+			&GobCodec{},
+			&BinaryCodec{},
+		} {
+			store := NewRedisHashStore(rhs.client, nil)
+			store.Codec = codec
+			var buf []byte
+			if codec != nil {
+				buf, _ = encodeData(codec, M{
+					"name":  "tree.xie",
+					"count": 1,
+				})
+			} else {
+				buf, _ = json.Marshal(M{
+					"name":  "tree.xie",
+					"count": 1,
+				})
+			}
+			id := generateID()
+			err := store.Set(id, buf, ttl)
+			if err != nil {
+				t.Fatalf("set data fail, %v", err)
+			}
+			buf, err = store.Get(id)
+			if err != nil {
+				t.Fatalf("get data fail, %v", err)
+			}
+			m := make(M)
+			err = decodeData(buf, &m, nil)
+			if err != nil || m["name"] != "tree.xie" {
+				t.Fatalf("the data should be the same after round trip, %v", err)
+			}
+			count, err := store.Incr(id, "count", 2, ttl)
+			if err != nil || count != 3 {
+				t.Fatalf("incr fail, %v", err)
+			}
+			values, err := store.AddToSet(id, "tags", "a", ttl)
+			if err != nil || len(values) != 1 {
+				t.Fatalf("add to set fail, %v", err)
+			}
+			err = store.SetFields(id, M{
+				"age": 18,
+			}, nil, ttl)
+			if err != nil {
+				t.Fatalf("set fields fail, %v", err)
+			}
+			buf, _ = store.Get(id)
+			m = make(M)
+			decodeData(buf, &m, nil)
+			if m["name"] != "tree.xie" || cast.ToInt(m["count"]) != 3 || cast.ToInt(m["age"]) != 18 || len(m["tags"].([]interface{})) != 1 {
+				t.Fatalf("the fields should be the same after round trip")
+			}
+			store.Destroy(id)
+		}
+	})
+
+	t.Run("unregistered codec", func(t *testing.T) {
+		store := NewRedisHashStore(rhs.client, nil)
+		store.Codec = &customCodec{}
+		err := store.Set(generateID(), data, ttl)
+		if err != ErrCodecNotRegistered {
+			t.Fatalf("set data with unregistered codec should return error")
+		}
+	})
+
+	t.Run("atomic with codec", func(t *testing.T) {
+		store := NewRedisHashStore(rhs.client, nil)
+		store.Codec = &BinaryCodec{}
+		id := generateID()
+		_, err := store.Incr(id, "count", 1, ttl)
+		if err != ErrNotExists {
+			t.Fatalf("incr not exists session should return not exists error")
+		}
+		buf, _ := encodeData(store.Codec, M{
+			"count": int64(1),
+		})
+		store.Set(id, buf, ttl)
+		count, err := store.Incr(id, "count", 2, ttl)
+		if err != nil || count != 3 {
+			t.Fatalf("incr with codec fail, %v", err)
+		}
+		// the modification by others should be retried
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := store.Incr(id, "count", 1, ttl)
+				if err != nil {
+					t.Errorf("incr with codec concurrently fail, %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+		count, _ = store.Incr(id, "count", 0, ttl)
+		if count != 13 {
+			t.Fatalf("incr with codec should be executed atomically")
+		}
+		values, err := store.Push(id, "viewed", "a", 1, ttl)
+		if err != nil || len(values) != 1 {
+			t.Fatalf("push with codec fail, %v", err)
+		}
+		store.Destroy(id)
+	})
+
+	t.Run("session", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.Header.Set(HeaderSessionID, key)
+		w := httptest.NewRecorder()
+		sess := NewWithTransport(NewHeaderTransport(r, w, HeaderSessionID), &Options{
+			Store:  rhs,
+			MaxAge: ttl,
+		})
+		m, err := sess.Fetch()
+		if err != nil || m["age"].(float64) != 20 {
+			t.Fatalf("fetch session from redis hash fail, %v", err)
+		}
+		sess.Set("name", "tree.xie")
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session to redis hash fail, %v", err)
+		}
+		result, _ := rhs.client.HGetAll(key).Result()
+		if result["name"] != `"tree.xie"` || result["age"] != "20" || result[UpdatedAt] == "" {
+			t.Fatalf("the session should be committed partially")
+		}
+	})
+
+	t.Run("session without max age", func(t *testing.T) {
+		id := generateID()
+		sess := newHeaderSession(rhs, id, Options{})
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session without max age fail, %v", err)
+		}
+		id = sess.GetID()
+
+		// commit partially
+		sess = newHeaderSession(rhs, id, Options{})
+		sess.Fetch()
+		sess.Set("age", 18)
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session without max age fail, %v", err)
+		}
+		_, err = sess.Incr("count", 1)
+		if err != nil {
+			t.Fatalf("incr session without max age fail, %v", err)
+		}
+		_, err = sess.Push("viewed", "a", 10)
+		if err != nil {
+			t.Fatalf("push session without max age fail, %v", err)
+		}
+		err = rhs.Touch(id, 0)
+		if err != nil {
+			t.Fatalf("touch session without max age fail, %v", err)
+		}
+		result, _ := rhs.client.HGetAll(id).Result()
+		if result["name"] != `"tree.xie"` || result["age"] != "18" || result["count"] != "1" || result["viewed"] == "" {
+			t.Fatalf("the session without max age should not be removed")
+		}
+		d, _ := rhs.client.TTL(id).Result()
+		if d >= 0 {
+			t.Fatalf("the session without max age should not expire")
+		}
+		rhs.Destroy(id)
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		store := NewRedisHashStore(rhs.client, nil)
+		store.Prefix = "sess:"
+		id := generateID()
+		err := store.Set(id, data, ttl)
+		if err != nil {
+			t.Fatalf("set data with prefix fail, %v", err)
+		}
+		name, _ := rhs.client.HGet("sess:"+id, "name").Result()
+		if name != `"tree.xie"` {
+			t.Fatalf("the key of session should be prefixed")
+		}
+		count, err := store.Incr(id, "count", 1, ttl)
+		if err != nil || count != 1 {
+			t.Fatalf("incr with prefix fail, %v", err)
+		}
+		n, _ := store.TTL(id)
+		if n <= 0 {
+			t.Fatalf("get ttl with prefix fail")
+		}
+		store.Destroy(id)
+		buf, _ := store.Get(id)
+		if len(buf) != 0 {
+			t.Fatalf("destroy with prefix fail")
+		}
+	})
+
+	t.Run("touch", func(t *testing.T) {
+		rhs.client.Expire(key, 10*time.Second)
+		err := rhs.Touch(key, ttl)
+		if err != nil {
+			t.Fatalf("touch data fail, %v", err)
+		}
+		d, _ := rhs.client.TTL(key).Result()
+		if d < 10*time.Second {
+			t.Fatalf("the ttl should be updated after touch")
+		}
+	})
+
+	t.Run("destroy", func(t *testing.T) {
+		err := rhs.DestroyContext(context.Background(), key)
+		if err != nil {
+			t.Fatalf("destory data fail, %v", err)
+		}
+		buf, err := rhs.GetContext(context.Background(), key)
+		if err != nil || len(buf) != 0 {
+			t.Fatalf("shoud return empty bytes after destroy")
+		}
+		err = rhs.SetContext(context.Background(), key, data, ttl)
+		if err != nil {
+			t.Fatalf("set data with context fail, %v", err)
+		}
+		err = rhs.Destroy(key)
+		if err != nil {
+			t.Fatalf("destory data fail, %v", err)
+		}
+	})
+}
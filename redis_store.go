@@ -1,21 +1,143 @@
 package session
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis"
+	"github.com/spf13/cast"
+)
+
+const (
+	// the suffix of version key
+	versionKeySuffix = ".version"
+	// the prefix of user index key
+	userIndexKeyPrefix = "sess-user:"
+	// the channel of expired keyevent notification
+	expiredChannel = "__keyevent@%d__:expired"
+)
+
+var (
+	// addUserSessionScript add the session to the set of user, the ttl of set
+	// is only extended, and the set which has been persisted(-1) isn't expired.
+	// The ttl of set should be read before SADD, otherwise the new set is -1 too
+	addUserSessionScript = redis.NewScript(`
+local ttl = redis.call("TTL", KEYS[1])
+redis.call("SADD", KEYS[1], ARGV[1])
+local expiration = tonumber(ARGV[2])
+if expiration <= 0 then
+	redis.call("PERSIST", KEYS[1])
+elseif ttl == -2 or (ttl >= 0 and ttl < expiration) then
+	redis.call("EXPIRE", KEYS[1], expiration)
+end
+return 1
+`)
 )
 
 type (
 	// RedisStore redis store for session
 	RedisStore struct {
 		client *redis.Client
+		// the prefix of session key in redis, only the expired keys with the prefix
+		// are notified, so the database can be shared with other keys
+		Prefix string
 	}
 )
 
+func getVersionKey(key string) string {
+	return key + versionKeySuffix
+}
+
+func getUserIndexKey(prefix, userID string) string {
+	return prefix + userIndexKeyPrefix + userID
+}
+
+// addUserSession add the session to the set of user, the ttl of set
+// will be extended if it's less than the ttl of session, and the set
+// will be persisted if the session has no ttl(ttl <= 0). The persisted
+// set is never expired, so the session without ttl won't be lost
+func addUserSession(client *redis.Client, prefix, userID, key string, ttl int) error {
+	return addUserSessionScript.Run(client, []string{getUserIndexKey(prefix, userID)}, key, ttl).Err()
+}
+
+// notifyExpired subscribe the expired keyevent notification of redis, the key
+// without the prefix, the version key and user index key are ignored
+func notifyExpired(client *redis.Client, prefix string, fn func(string, []byte)) (stop func(), err error) {
+	pubsub := client.Subscribe(fmt.Sprintf(expiredChannel, client.Options().DB))
+	// wait for the subscription is created
+	_, err = pubsub.Receive()
+	if err != nil {
+		pubsub.Close()
+		return
+	}
+	ch := pubsub.Channel()
+	go func() {
+		for msg := range ch {
+			if !strings.HasPrefix(msg.Payload, prefix) {
+				continue
+			}
+			key := msg.Payload[len(prefix):]
+			if strings.HasSuffix(key, versionKeySuffix) || strings.HasPrefix(key, userIndexKeyPrefix) {
+				continue
+			}
+			fn(key, nil)
+		}
+	}()
+	once := sync.Once{}
+	stop = func() {
+		once.Do(func() {
+			pubsub.Close()
+		})
+	}
+	return
+}
+
+// removeUserSession remove the session from the set of user
+func removeUserSession(client *redis.Client, prefix, userID, key string) error {
+	return client.SRem(getUserIndexKey(prefix, userID), key).Err()
+}
+
+// userSessions get the sessions from the set of user,
+// the expired sessions will be pruned from the set
+func userSessions(client *redis.Client, prefix, userID string) (keys []string, err error) {
+	indexKey := getUserIndexKey(prefix, userID)
+	members, err := client.SMembers(indexKey).Result()
+	if err != nil || len(members) == 0 {
+		return
+	}
+	cmds := make([]*redis.IntCmd, len(members))
+	_, err = client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, key := range members {
+			cmds[i] = pipe.Exists(prefix + key)
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	expired := make([]interface{}, 0)
+	for i, key := range members {
+		if cmds[i].Val() == 0 {
+			expired = append(expired, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(expired) != 0 {
+		err = client.SRem(indexKey, expired...).Err()
+	}
+	sort.Strings(keys)
+	return
+}
+
 // Get get the session from redis
 func (rs *RedisStore) Get(key string) ([]byte, error) {
+	key = rs.Prefix + key
 	buf, err := rs.client.Get(key).Bytes()
 	if err == redis.Nil {
 		return buf, nil
@@ -23,15 +145,139 @@ func (rs *RedisStore) Get(key string) ([]byte, error) {
 	return buf, err
 }
 
+// GetContext get the session from redis with context
+func (rs *RedisStore) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return getContext(ctx, key, rs.Get)
+}
+
 // Set set the session to redis
 func (rs *RedisStore) Set(key string, data []byte, ttl int) error {
+	key = rs.Prefix + key
 	expiration := time.Duration(int64(time.Second) * int64(ttl))
 	return rs.client.Set(key, data, expiration).Err()
 }
 
+// SetContext set the session to redis with context
+func (rs *RedisStore) SetContext(ctx context.Context, key string, data []byte, ttl int) error {
+	return runContext(ctx, func() error {
+		return rs.Set(key, data, ttl)
+	})
+}
+
+// GetVersion get the session and its version from redis, the version key
+// is only written by SetVersion, so the version of the session which is
+// set by Set is 0
+func (rs *RedisStore) GetVersion(key string) (data []byte, version int64, err error) {
+	key = rs.Prefix + key
+	values, err := rs.client.MGet(key, getVersionKey(key)).Result()
+	if err != nil {
+		return
+	}
+	if values[0] == nil {
+		return
+	}
+	data = []byte(cast.ToString(values[0]))
+	if values[1] != nil {
+		version = cast.ToInt64(values[1])
+	}
+	return
+}
+
+// SetVersion set the session to redis if the stored version is equal to the version
+func (rs *RedisStore) SetVersion(key string, data []byte, ttl int, version int64) (err error) {
+	key = rs.Prefix + key
+	expiration := time.Duration(int64(time.Second) * int64(ttl))
+	versionKey := getVersionKey(key)
+	err = rs.client.Watch(func(tx *redis.Tx) error {
+		values, err := tx.MGet(key, versionKey).Result()
+		if err != nil {
+			return err
+		}
+		var current int64
+		// the version is useless if the data is not exists
+		if values[0] != nil && values[1] != nil {
+			current = cast.ToInt64(values[1])
+		}
+		if current != version {
+			return ErrConflict
+		}
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set(key, data, expiration)
+			pipe.Set(versionKey, version+1, expiration)
+			return nil
+		})
+		return err
+	}, key, versionKey)
+	if err == redis.TxFailedErr {
+		err = ErrConflict
+	}
+	return
+}
+
+// getTTL get the remaining ttl(seconds) of the key
+func getTTL(client *redis.Client, key string) (int, error) {
+	d, err := client.TTL(key).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(d / time.Second), nil
+}
+
+// TTL get the remaining ttl(seconds) of the session
+func (rs *RedisStore) TTL(key string) (int, error) {
+	return getTTL(rs.client, rs.Prefix+key)
+}
+
+// Touch update the ttl of the session, the session without ttl(ttl <= 0) isn't changed
+func (rs *RedisStore) Touch(key string, ttl int) error {
+	key = rs.Prefix + key
+	if ttl <= 0 {
+		return nil
+	}
+	expiration := time.Duration(int64(time.Second) * int64(ttl))
+	_, err := rs.client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Expire(key, expiration)
+		pipe.Expire(getVersionKey(key), expiration)
+		return nil
+	})
+	return err
+}
+
+// AddUserSession add the session to the index of user
+func (rs *RedisStore) AddUserSession(userID, key string, ttl int) error {
+	return addUserSession(rs.client, rs.Prefix, userID, key, ttl)
+}
+
+// RemoveUserSession remove the session from the index of user
+func (rs *RedisStore) RemoveUserSession(userID, key string) error {
+	return removeUserSession(rs.client, rs.Prefix, userID, key)
+}
+
+// UserSessions get the sessions of user
+func (rs *RedisStore) UserSessions(userID string) ([]string, error) {
+	return userSessions(rs.client, rs.Prefix, userID)
+}
+
+// NotifyExpired call the function with the session id when the session is expired,
+// the keyspace notification of redis should be enabled(notify-keyspace-events Ex).
+// All the expired keys of database are notified if the prefix isn't set, so a
+// dedicated database should be used. The data of expired session can't be got,
+// so it's always nil.
+func (rs *RedisStore) NotifyExpired(fn func(key string, data []byte)) (stop func(), err error) {
+	return notifyExpired(rs.client, rs.Prefix, fn)
+}
+
 // Destroy remove the session from redis
 func (rs *RedisStore) Destroy(key string) error {
-	return rs.client.Del(key).Err()
+	key = rs.Prefix + key
+	return rs.client.Del(key, getVersionKey(key)).Err()
+}
+
+// DestroyContext remove the session from redis with context
+func (rs *RedisStore) DestroyContext(ctx context.Context, key string) error {
+	return runContext(ctx, func() error {
+		return rs.Destroy(key)
+	})
 }
 
 // NewRedisStore create new redis store instance
@@ -2,7 +2,9 @@ package session
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redis"
 )
@@ -55,4 +57,206 @@ func TestRedisStore(t *testing.T) {
 			t.Fatalf("shoud return empty bytes after destroy")
 		}
 	})
+	t.Run("context", func(t *testing.T) {
+		ctx := context.Background()
+		err := rs.SetContext(ctx, key, data, ttl)
+		if err != nil {
+			t.Fatalf("set data with context fail, %v", err)
+		}
+		buf, err := rs.GetContext(ctx, key)
+		if err != nil || !bytes.Equal(data, buf) {
+			t.Fatalf("get data with context fail, %v", err)
+		}
+		err = rs.DestroyContext(ctx, key)
+		if err != nil {
+			t.Fatalf("destroy data with context fail, %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = rs.GetContext(ctx, key)
+		if err != context.Canceled {
+			t.Fatalf("canceled context should return error")
+		}
+	})
+	t.Run("touch", func(t *testing.T) {
+		rs.Set(key, data, 10)
+		err := rs.Touch(key, ttl)
+		if err != nil {
+			t.Fatalf("touch data fail, %v", err)
+		}
+		d, _ := rs.client.TTL(key).Result()
+		if d < 10*time.Second {
+			t.Fatalf("the ttl should be updated after touch")
+		}
+		ttl, err := rs.TTL(key)
+		if err != nil || ttl < 10 {
+			t.Fatalf("get ttl fail, %v", err)
+		}
+
+		// the session without ttl should not be expired by touch
+		rs.Set(key, data, 0)
+		err = rs.Touch(key, 0)
+		if err != nil {
+			t.Fatalf("touch data without ttl fail, %v", err)
+		}
+		buf, _ := rs.Get(key)
+		if !bytes.Equal(data, buf) {
+			t.Fatalf("the session without ttl should not be removed by touch")
+		}
+	})
+	t.Run("version", func(t *testing.T) {
+		key := generateID()
+		_, version, err := rs.GetVersion(key)
+		if err != nil || version != 0 {
+			t.Fatalf("the version of not exists data should be 0")
+		}
+		err = rs.SetVersion(key, data, ttl, 0)
+		if err != nil {
+			t.Fatalf("set data with version fail, %v", err)
+		}
+		err = rs.SetVersion(key, data, ttl, 0)
+		if err != ErrConflict {
+			t.Fatalf("set data with stale version should return conflict error")
+		}
+		buf, version, err := rs.GetVersion(key)
+		if err != nil || version != 1 || !bytes.Equal(data, buf) {
+			t.Fatalf("get data with version fail, %v", err)
+		}
+		rs.Destroy(key)
+		n, _ := rs.client.Exists(getVersionKey(key)).Result()
+		if n != 0 {
+			t.Fatalf("the version should be removed after destroy")
+		}
+
+		// the version key is only written by SetVersion
+		rs.Set(key, data, 0)
+		n, _ = rs.client.Exists(getVersionKey(key)).Result()
+		if n != 0 {
+			t.Fatalf("set data should not write the version key")
+		}
+		_, version, _ = rs.GetVersion(key)
+		if version != 0 {
+			t.Fatalf("the version of data set by Set should be 0")
+		}
+		d, _ := rs.client.TTL(key).Result()
+		if d >= 0 {
+			t.Fatalf("the data set without ttl should not expire")
+		}
+		rs.Destroy(key)
+	})
+	t.Run("user index without ttl", func(t *testing.T) {
+		userID := generateID()
+		key := generateID()
+		rs.Set(key, data, 0)
+		err := rs.AddUserSession(userID, key, 0)
+		if err != nil {
+			t.Fatalf("add user session without ttl fail, %v", err)
+		}
+		keys, err := rs.UserSessions(userID)
+		if err != nil || len(keys) != 1 || keys[0] != key {
+			t.Fatalf("the index of user should not be removed, %v", err)
+		}
+		indexKey := getUserIndexKey("", userID)
+		d, _ := rs.client.TTL(indexKey).Result()
+		if d >= 0 {
+			t.Fatalf("the index of user should not expire")
+		}
+		// the session without ttl is added after the session with ttl
+		rs.AddUserSession(userID, generateID(), ttl)
+		rs.AddUserSession(userID, key, 0)
+		d, _ = rs.client.TTL(indexKey).Result()
+		if d >= 0 {
+			t.Fatalf("the index of user should be persisted")
+		}
+		// the session with ttl is added after the session without ttl
+		rs.AddUserSession(userID, generateID(), 2)
+		d, _ = rs.client.TTL(indexKey).Result()
+		if d >= 0 {
+			t.Fatalf("the persisted index of user should not be expired")
+		}
+		rs.Destroy(key)
+		rs.client.Del(indexKey)
+
+		// the ttl of index is only extended
+		rs.AddUserSession(userID, generateID(), ttl)
+		rs.AddUserSession(userID, generateID(), 2)
+		d, _ = rs.client.TTL(indexKey).Result()
+		if d <= 2*time.Second {
+			t.Fatalf("the ttl of index should not be shortened")
+		}
+		rs.client.Del(indexKey)
+	})
+
+	t.Run("notify expired", func(t *testing.T) {
+		expired := make(chan string, 10)
+		stop, err := rs.NotifyExpired(func(key string, data []byte) {
+			expired <- key
+		})
+		if err != nil {
+			t.Fatalf("notify expired fail, %v", err)
+		}
+		defer stop()
+		channel := "__keyevent@0__:expired"
+		rs.client.Publish(channel, getVersionKey(key))
+		rs.client.Publish(channel, getUserIndexKey("", "tree.xie"))
+		rs.client.Publish(channel, key)
+		select {
+		case k := <-expired:
+			if k != key {
+				t.Fatalf("the version key and user index key should be ignored")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("the expired session should be notified")
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		store := NewRedisStore(rs.client, nil)
+		store.Prefix = "sess:"
+		id := generateID()
+		err := store.Set(id, data, ttl)
+		if err != nil {
+			t.Fatalf("set data with prefix fail, %v", err)
+		}
+		buf, _ := rs.Get("sess:" + id)
+		if !bytes.Equal(data, buf) {
+			t.Fatalf("the key of session should be prefixed")
+		}
+		buf, _ = store.Get(id)
+		if !bytes.Equal(data, buf) {
+			t.Fatalf("get data with prefix fail")
+		}
+		userID := generateID()
+		store.AddUserSession(userID, id, ttl)
+		keys, _ := store.UserSessions(userID)
+		if len(keys) != 1 || keys[0] != id {
+			t.Fatalf("the index of user should keep the session id without prefix")
+		}
+		store.RemoveUserSession(userID, id)
+		store.Destroy(id)
+
+		expired := make(chan string, 10)
+		stop, err := store.NotifyExpired(func(key string, data []byte) {
+			expired <- key
+		})
+		if err != nil {
+			t.Fatalf("notify expired fail, %v", err)
+		}
+		defer stop()
+		channel := "__keyevent@0__:expired"
+		rs.client.Publish(channel, "cache:"+id)
+		rs.client.Publish(channel, id)
+		rs.client.Publish(channel, "sess:"+getVersionKey(id))
+		rs.client.Publish(channel, getUserIndexKey("sess:", userID))
+		rs.client.Publish(channel, "sess:"+id)
+		select {
+		case k := <-expired:
+			if k != id {
+				t.Fatalf("only the expired session with prefix should be notified, %s", k)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("the expired session should be notified")
+		}
+	})
 }
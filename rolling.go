@@ -0,0 +1,96 @@
+package session
+
+import (
+	"context"
+	"time"
+)
+
+// shouldRoll check the session should be rolled
+func (sess *Session) shouldRoll() bool {
+	opts := sess.opts
+	if opts == nil || !opts.Rolling || !sess.fetched || !sess.stored || sess.cookieValue == "" {
+		return false
+	}
+	if opts.RollingInterval <= 0 || sess.canTouch() {
+		return true
+	}
+	activeAt, ok := getLastActiveTime(sess.data)
+	if !ok {
+		return true
+	}
+	return time.Since(activeAt) >= time.Duration(opts.RollingInterval)*time.Second
+}
+
+// canTouch check the session can be rolled by touching the store, if the rolling
+// interval is set, the store should be a TTLStore to check the interval
+func (sess *Session) canTouch() bool {
+	opts := sess.opts
+	if _, ok := opts.Store.(Toucher); !ok {
+		return false
+	}
+	if opts.RollingInterval <= 0 {
+		return true
+	}
+	_, ok := opts.Store.(TTLStore)
+	return ok
+}
+
+// shouldTouch check the session should be touched, if the rolling interval is set,
+// it should be touched only when the interval has passed since the ttl was set
+func (sess *Session) shouldTouch() (bool, error) {
+	opts := sess.opts
+	if opts.RollingInterval <= 0 {
+		return true, nil
+	}
+	ttl, err := opts.Store.(TTLStore).TTL(sess.cookieValue)
+	if err != nil {
+		return false, err
+	}
+	if ttl < 0 {
+		return true, nil
+	}
+	return sess.GetMaxAge()-ttl >= opts.RollingInterval, nil
+}
+
+// roll extend the ttl of session, if the store is a Toucher(and a TTLStore when
+// the rolling interval is set), it just touches the session at most once per interval.
+// Otherwise the updated at will be refreshed and the session should be rewritten(done is false).
+func (sess *Session) roll(ctx context.Context) (done bool, err error) {
+	opts := sess.opts
+	if sess.canTouch() {
+		err = ctx.Err()
+		if err != nil {
+			return
+		}
+		var touch bool
+		touch, err = sess.shouldTouch()
+		if err != nil {
+			return
+		}
+		// the session has been rolled in the interval
+		if !touch {
+			sess.committed = true
+			done = true
+			return
+		}
+		err = opts.Store.(Toucher).Touch(sess.cookieValue, sess.GetMaxAge())
+		if err != nil {
+			return
+		}
+		// extend the ttl of user index
+		err = sess.addUser(sess.GetUserID())
+		if err != nil {
+			return
+		}
+		sess.addSessionCookie(sess.cookieValue)
+		sess.committed = true
+		done = true
+		emit(sess.getHooks().OnRefresh, sess.cookieValue, sess.data, ReasonRolling)
+		return
+	}
+	sess.data[UpdatedAt] = time.Now().Format(time.RFC3339)
+	sess.markDirty(UpdatedAt)
+	sess.addSessionCookie(sess.cookieValue)
+	emit(sess.getHooks().OnRefresh, sess.cookieValue, sess.data, ReasonRolling)
+	return
+}
@@ -0,0 +1,166 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/vicanso/cookies"
+)
+
+func TestRolling(t *testing.T) {
+	hourAgo := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	t.Run("touch store", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		id := generateID()
+		sess, w := newCookieSession(ms, id, M{
+			CreatedAt: hourAgo,
+		}, 10, &Options{
+			MaxAge:  3600,
+			Rolling: true,
+		})
+		sess.Fetch()
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit rolling session fail, %v", err)
+		}
+		v, _ := ms.client.Get(id)
+		info := v.(*MemoryStoreInfo)
+		if info.ExpiredAt < time.Now().Unix()+3000 {
+			t.Fatalf("rolling session should be touched")
+		}
+		m := make(M)
+		json.Unmarshal(info.Data, &m)
+		if m[UpdatedAt] != nil {
+			t.Fatalf("touch session should not rewrite data")
+		}
+		if len(w.HeaderMap["Set-Cookie"]) == 0 {
+			t.Fatalf("rolling session should refresh cookie")
+		}
+	})
+
+	t.Run("not store", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		sess := New(cookies.NewHTTPReadWriter(r, w), &Options{
+			Store:   ms,
+			Rolling: true,
+		})
+		sess.Fetch()
+		sess.Commit()
+		if len(w.HeaderMap["Set-Cookie"]) != 0 {
+			t.Fatalf("new session should not be rolled")
+		}
+	})
+
+	t.Run("rolling interval", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		id := generateID()
+		opts := &Options{
+			MaxAge:          3600,
+			Rolling:         true,
+			RollingInterval: 60,
+		}
+		sess, _ := newCookieSession(ms, id, M{
+			CreatedAt: hourAgo,
+			UpdatedAt: hourAgo,
+		}, 10, opts)
+		// the ttl was set 10 seconds ago
+		buf, _ := ms.Get(id)
+		ms.Set(id, buf, 3590)
+		sess.Fetch()
+		sess.Commit()
+		v, _ := ms.client.Get(id)
+		if v.(*MemoryStoreInfo).ExpiredAt > time.Now().Unix()+3595 {
+			t.Fatalf("session should not be rolled in the interval")
+		}
+
+		// the ttl was set 600 seconds ago
+		sess, _ = newCookieSession(ms, id, M{
+			CreatedAt: hourAgo,
+			UpdatedAt: hourAgo,
+		}, 10, opts)
+		ms.Set(id, buf, 3000)
+		sess.Fetch()
+		sess.Commit()
+		v, _ = ms.client.Get(id)
+		info := v.(*MemoryStoreInfo)
+		if info.ExpiredAt < time.Now().Unix()+3500 {
+			t.Fatalf("session should be rolled after the interval")
+		}
+		m := make(M)
+		json.Unmarshal(info.Data, &m)
+		if m[UpdatedAt] != hourAgo {
+			t.Fatalf("the session should be touched without rewriting")
+		}
+	})
+
+	t.Run("rolling interval without ttl", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		store := NewCompressedStore(ms, CompressionGzip, 0)
+		id := generateID()
+		now := time.Now().Format(time.RFC3339)
+		sess, _ := newCookieSession(store, id, M{
+			CreatedAt: hourAgo,
+			UpdatedAt: now,
+		}, 10, &Options{
+			MaxAge:          3600,
+			Rolling:         true,
+			RollingInterval: 60,
+		})
+		sess.Fetch()
+		sess.Commit()
+		v, _ := ms.client.Get(id)
+		if v.(*MemoryStoreInfo).ExpiredAt > time.Now().Unix()+10 {
+			t.Fatalf("session should not be rolled in the interval")
+		}
+
+		sess, _ = newCookieSession(store, id, M{
+			CreatedAt: hourAgo,
+			UpdatedAt: hourAgo,
+		}, 10, &Options{
+			MaxAge:          3600,
+			Rolling:         true,
+			RollingInterval: 60,
+		})
+		sess.Fetch()
+		sess.Commit()
+		v, _ = ms.client.Get(id)
+		info := v.(*MemoryStoreInfo)
+		if info.ExpiredAt < time.Now().Unix()+3000 {
+			t.Fatalf("session should be rolled after the interval")
+		}
+		buf, _ := store.Get(id)
+		m := make(M)
+		json.Unmarshal(buf, &m)
+		if m[UpdatedAt] == hourAgo {
+			t.Fatalf("updated at should be refreshed")
+		}
+	})
+
+	t.Run("redis store without max age", func(t *testing.T) {
+		rs := NewRedisStore(nil, &redis.Options{
+			Addr: "localhost:6379",
+		})
+		id := generateID()
+		sess, _ := newCookieSession(rs, id, M{
+			CreatedAt: hourAgo,
+		}, 10, &Options{
+			Rolling: true,
+		})
+		sess.Fetch()
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit rolling session fail, %v", err)
+		}
+		buf, _ := rs.Get(id)
+		if len(buf) == 0 {
+			t.Fatalf("the session should not be removed by rolling")
+		}
+	})
+}
@@ -1,9 +1,8 @@
 package session
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"math/rand"
 	"time"
 
 	"github.com/spf13/cast"
@@ -16,11 +15,25 @@ const (
 	CreatedAt = "_createdAt"
 	// UpdatedAt the updated time for session
 	UpdatedAt = "_updatedAt"
+	// MaxAge the max age for session, it overrides the Options.MaxAge
+	MaxAge = "_maxAge"
+	// Flash the flash messages for session
+	Flash = "_flash"
+	// UserID the user id which the session is bound to
+	UserID = "_userID"
 )
 
 var (
 	// ErrNotFetched not fetch error
 	ErrNotFetched = errors.New("Not fetch session")
+	// ErrConflict the session has been modified by others
+	ErrConflict = errors.New("session conflict")
+	// ErrNotExists the session data doesn't exist in store
+	ErrNotExists = errors.New("session not exists")
+	// ErrNotCASStore the store of versioned session should be a CASStore
+	ErrNotCASStore = errors.New("the store of versioned session should be a cas store")
+	// ErrNotUserIndex the store of session bound to user should be an UserIndex
+	ErrNotUserIndex = errors.New("the store of session bound to user should be an user index")
 )
 
 type (
@@ -35,6 +48,85 @@ type (
 		// Destroy remove the session data
 		Destroy(string) error
 	}
+	// StoreContext session store interface with context,
+	// the operation should be canceled when the context is done
+	StoreContext interface {
+		// GetContext get the session data
+		GetContext(context.Context, string) ([]byte, error)
+		// SetContext set the session data
+		SetContext(context.Context, string, []byte, int) error
+		// DestroyContext remove the session data
+		DestroyContext(context.Context, string) error
+	}
+	// Toucher the store which can extend the ttl of session without rewriting data
+	Toucher interface {
+		// Touch update the ttl of the session data
+		Touch(string, int) error
+	}
+	// TTLStore the store which can get the remaining ttl of session
+	TTLStore interface {
+		// TTL get the remaining ttl(seconds) of the session data,
+		// it's negative if the session data has no ttl or doesn't exist
+		TTL(string) (int, error)
+	}
+	// CASStore the store which supports compare-and-set by version
+	CASStore interface {
+		// GetVersion get the session data and its version
+		GetVersion(string) ([]byte, int64, error)
+		// SetVersion set the session data if the stored version is equal to
+		// the version, and the stored version will be increased,
+		// otherwise ErrConflict should be returned
+		SetVersion(string, []byte, int, int64) error
+	}
+	// PartialStore the store which can update the fields of session data partially
+	PartialStore interface {
+		// SetFields set the changed fields and remove the deleted fields
+		// of the session data, the ttl of session data should be updated too
+		SetFields(key string, fields M, deleted []string, ttl int) error
+	}
+	// AtomicStore the store which can modify the field of session data atomically,
+	// ErrNotExists should be returned if the session data doesn't exist
+	AtomicStore interface {
+		// Incr increase the field by delta and return the new value
+		Incr(key, field string, delta int64, ttl int) (int64, error)
+		// Push prepend the value to the list field, the list will be trimmed
+		// to max length if it's greater than 0, and return the new list
+		Push(key, field string, value interface{}, maxLen int, ttl int) ([]interface{}, error)
+		// AddToSet add the value to the set field if it doesn't exist, and return the new set
+		AddToSet(key, field string, value interface{}, ttl int) ([]interface{}, error)
+	}
+	// UserIndex the store which can index the sessions of user
+	UserIndex interface {
+		// AddUserSession add the session to the index of user, the ttl is
+		// the max age of session
+		AddUserSession(userID, key string, ttl int) error
+		// RemoveUserSession remove the session from the index of user
+		RemoveUserSession(userID, key string) error
+		// UserSessions get the sessions of user, the expired sessions should be excluded
+		UserSessions(userID string) ([]string, error)
+	}
+	// ExpiryNotifier the store which can notify the expired sessions
+	ExpiryNotifier interface {
+		// NotifyExpired call the function with the session id and its data(nil if
+		// it can't be got) when the session is expired, stop should be called
+		// to stop the notification
+		NotifyExpired(fn func(key string, data []byte)) (stop func(), err error)
+	}
+	// Transport the transport to read and write session id
+	Transport interface {
+		// Get get the session id by name
+		Get(string) string
+		// Set set the session id by name, the max age(seconds) is the
+		// max age of session, 0 means using the default
+		Set(string, string, int)
+	}
+	// Codec codec to encode and decode session data
+	Codec interface {
+		// ID the id of codec, it's saved as the version byte of data
+		ID() byte
+		Unmarshal([]byte, interface{}) error
+		Marshal(interface{}) ([]byte, error)
+	}
 	// JSON json Unmarshal/Marshal
 	JSON interface {
 		Unmarshal([]byte, interface{}) error
@@ -46,27 +138,81 @@ type (
 		Key string
 		// the max age for session data
 		MaxAge int
+		// the idle timeout(seconds) for session, it's renewed when the session is fetched or updated
+		IdleTimeout int
+		// the absolute timeout(seconds) for session, it's measured from the created time
+		AbsoluteTimeout int
 		// the session store
 		Store Store
-		// function to generate session id
-		GenID         func() string
-		CookieOptions *cookies.Options
+		// function to generate session id,
+		// default is crypto random id generated by IDSize and IDEncoding
+		GenID func() string
+		// the entropy size(bytes) of default session id, default is 18
+		IDSize int
+		// the encoding of default session id, default is base62
+		IDEncoding IDEncoding
+		// function to validate the session id from client,
+		// the invalid id will be rejected before store lookup
+		ValidateID func(string) bool
+		// strict mode, the session id from client which is not found in store
+		// will be discarded, and a new one will be generated when commit
+		Strict bool
+		// function to be called when the unknown session id is discarded in strict mode
+		OnUnknownID func(string)
+		// rolling session, the ttl of session will be extended when commit
+		// even if the data isn't modified. If the store is a Toucher,
+		// it will just touch the session without rewriting data
+		Rolling bool
+		// the interval(seconds) of rolling session, if it's set, the session will be
+		// rolled at most once per interval. It's touched if the store is a Toucher
+		// and a TTLStore, otherwise it's rewritten with new updated at
+		RollingInterval int
+		CookieOptions   *cookies.Options
 		// JSON json Unmarshal/Marshal interface
 		JSON JSON
+		// enable optimistic concurrency control, the store should implement CASStore(otherwise
+		// ErrNotCASStore is returned), ErrConflict will be returned by commit if the stored version has moved
+		Versioned bool
+		// function to merge the stored data and the local data when commit conflicts,
+		// the commit will be retried with the merged data
+		Merge func(stored, local M) (M, error)
+		// the max sessions of user, it works with BindUser and the store should be
+		// an UserIndex(otherwise ErrNotUserIndex is returned), 0 means no limit
+		MaxUserSessions int
+		// the policy when the sessions of user exceed the limit,
+		// default is evicting the oldest session
+		UserSessionPolicy UserSessionPolicy
+		// function to be called when the session of user is evicted
+		OnEvict func(userID, id string)
+		// the lifecycle hooks of session
+		Hooks *Hooks
+		// Codec the codec to encode session data, the id of codec is saved
+		// as the version byte of data. It should be registered by RegisterCodec,
+		// otherwise ErrCodecNotRegistered will be returned by fetch and commit.
+		// If it's not set, the data will be encoded by JSON without version byte
+		Codec Codec
 	}
 	// Session session struct
 	Session struct {
-		opts    *Options
-		cookies *cookies.Cookies
-		signed  bool
+		opts      *Options
+		transport Transport
+		signed    bool
 		// the session cookie value
 		cookieValue string
+		// the previous session cookie value, it will be removed after commit
+		prevCookieValue string
 		// the data fetch from session
 		data M
 		// the data has been fetched
 		fetched bool
+		// the data is loaded from store or has been committed to store
+		stored bool
+		// the version of session data, it's used for optimistic concurrency control
+		version int64
 		// the data has been modified
 		modified bool
+		// the keys of data which have been added, changed or deleted
+		dirty map[string]bool
 		// the session has been committed
 		committed bool
 	}
@@ -114,55 +260,111 @@ func (sess *Session) getCookieName() string {
 func (sess *Session) getCookieValue() string {
 	cookieName := sess.getCookieName()
 
-	return sess.cookies.Get(cookieName, sess.signed)
+	return sess.transport.Get(cookieName)
+}
+
+// getStore get the context store of session
+func (sess *Session) getStore() StoreContext {
+	return getStoreContext(sess.opts.Store)
 }
 
 // Fetch fetch the session data from store
-func (sess *Session) Fetch() (m M, err error) {
+func (sess *Session) Fetch() (M, error) {
+	return sess.FetchContext(context.Background())
+}
+
+// FetchContext fetch the session data from store with context
+func (sess *Session) FetchContext(ctx context.Context) (m M, err error) {
 	if sess.fetched {
 		m = sess.data
 		return
 	}
 	opts := sess.opts
+	err = sess.checkCodec()
+	if err != nil {
+		return
+	}
 
 	value := sess.getCookieValue()
+	if value != "" && opts.ValidateID != nil && !opts.ValidateID(value) {
+		value = ""
+	}
 	var buf []byte
 	if value != "" {
 		sess.cookieValue = value
-		buf, err = opts.Store.Get(sess.cookieValue)
+		buf, err = sess.load(ctx, sess.cookieValue)
 		if err != nil {
 			return
 		}
+		// the session id is not issued by server
+		if len(buf) == 0 && opts.Strict {
+			sess.cookieValue = ""
+			if opts.OnUnknownID != nil {
+				opts.OnUnknownID(value)
+			}
+		}
 	}
 	m = make(M)
-	unmarshal := json.Unmarshal
-	if opts.JSON != nil {
-		unmarshal = opts.JSON.Unmarshal
-	}
 	if len(buf) == 0 {
 		m = getInitMap()
 	} else {
-		err = unmarshal(buf, &m)
+		err = sess.unmarshal(buf, &m)
 	}
 	if err != nil {
 		return
 	}
+	// the session is timeout, destroy it and use a new one
+	if reason, timeout := sess.isTimeout(m); len(buf) != 0 && timeout {
+		err = sess.getStore().DestroyContext(ctx, sess.cookieValue)
+		if err != nil {
+			return
+		}
+		err = sess.removeUser(cast.ToString(m[UserID]), sess.cookieValue)
+		if err != nil {
+			return
+		}
+		emit(sess.getHooks().OnExpire, sess.cookieValue, m, reason)
+		sess.cookieValue = ""
+		sess.version = 0
+		m = getInitMap()
+		buf = nil
+	}
+	sess.stored = len(buf) != 0
+	sess.dirty = nil
 	sess.fetched = true
 	sess.data = m
+	// the fetch is an activity of session, renew the idle timeout
+	if sess.stored && opts.IdleTimeout > 0 {
+		m[UpdatedAt] = time.Now().Format(time.RFC3339)
+		sess.markDirty(UpdatedAt)
+	}
+	if sess.stored {
+		emit(sess.getHooks().OnLoad, sess.cookieValue, m, ReasonFetch)
+	}
 	return
 }
 
 // Destroy remove the data from store and reset session data
-func (sess *Session) Destroy() (err error) {
-	opts := sess.opts
+func (sess *Session) Destroy() error {
+	return sess.DestroyContext(context.Background())
+}
+
+// DestroyContext remove the data from store with context and reset session data
+func (sess *Session) DestroyContext(ctx context.Context) (err error) {
 	value := sess.getCookieValue()
 	if value == "" {
 		return
 	}
-	err = opts.Store.Destroy(value)
+	err = sess.getStore().DestroyContext(ctx, value)
+	if err != nil {
+		return
+	}
+	err = sess.removeUser(cast.ToString(sess.data[UserID]), value)
 	if err != nil {
 		return
 	}
+	emit(sess.getHooks().OnDestroy, value, sess.data, ReasonDestroy)
+	sess.stored = false
 	m := getInitMap()
 	sess.data = m
 	return
@@ -182,7 +384,7 @@ func (sess *Session) Set(key string, value interface{}) (err error) {
 		sess.data[key] = value
 	}
 	sess.data[UpdatedAt] = time.Now().Format(time.RFC3339)
-	sess.modified = true
+	sess.markDirty(key, UpdatedAt)
 	return
 }
 
@@ -195,6 +397,7 @@ func (sess *Session) SetMap(value map[string]interface{}) (err error) {
 		return ErrNotFetched
 	}
 	for k, v := range value {
+		sess.markDirty(k)
 		if v == nil {
 			delete(sess.data, k)
 			continue
@@ -203,7 +406,7 @@ func (sess *Session) SetMap(value map[string]interface{}) (err error) {
 	}
 
 	sess.data[UpdatedAt] = time.Now().Format(time.RFC3339)
-	sess.modified = true
+	sess.markDirty(UpdatedAt)
 	return
 }
 
@@ -213,20 +416,23 @@ func (sess *Session) Refresh() (err error) {
 		return ErrNotFetched
 	}
 	sess.data[UpdatedAt] = time.Now().Format(time.RFC3339)
-	sess.modified = true
+	sess.markDirty(UpdatedAt)
 	// 刷新cookie的max age
 	if sess.cookieValue != "" {
 		sess.addSessionCookie(sess.cookieValue)
 	}
+	emit(sess.getHooks().OnRefresh, sess.cookieValue, sess.data, ReasonRefresh)
 	return
 }
 
-// Get get data from session's data
+// Get get data from session's data, the dotted path(e.g. user.profile.locale)
+// is supported for nested map
 func (sess *Session) Get(key string) interface{} {
 	if !sess.fetched {
 		return nil
 	}
-	return sess.data[key]
+	v, _ := sess.lookup(key)
+	return v
 }
 
 // GetBool get bool data from session's data
@@ -234,7 +440,7 @@ func (sess *Session) GetBool(key string) bool {
 	if !sess.fetched {
 		return false
 	}
-	return cast.ToBool(sess.data[key])
+	return cast.ToBool(sess.Get(key))
 }
 
 // GetString get string data from session's data
@@ -242,7 +448,7 @@ func (sess *Session) GetString(key string) string {
 	if !sess.fetched {
 		return ""
 	}
-	return cast.ToString(sess.data[key])
+	return cast.ToString(sess.Get(key))
 }
 
 // GetInt get int data from session's data
@@ -250,7 +456,7 @@ func (sess *Session) GetInt(key string) int {
 	if !sess.fetched {
 		return 0
 	}
-	return cast.ToInt(sess.data[key])
+	return cast.ToInt(sess.Get(key))
 }
 
 // GetFloat64 get float64 data from session's data
@@ -258,7 +464,7 @@ func (sess *Session) GetFloat64(key string) float64 {
 	if !sess.fetched {
 		return 0
 	}
-	return cast.ToFloat64(sess.data[key])
+	return cast.ToFloat64(sess.Get(key))
 }
 
 // GetStringSlice get string slice data from session's data
@@ -266,7 +472,7 @@ func (sess *Session) GetStringSlice(key string) []string {
 	if !sess.fetched {
 		return nil
 	}
-	return cast.ToStringSlice(sess.data[key])
+	return cast.ToStringSlice(sess.Get(key))
 }
 
 // GetCreatedAt get the created at of session
@@ -294,28 +500,76 @@ func (sess *Session) GetUpdatedAt() string {
 }
 
 // Commit sync the session to store
-func (sess *Session) Commit() (err error) {
-	if !sess.modified || sess.committed {
+func (sess *Session) Commit() error {
+	return sess.CommitContext(context.Background())
+}
+
+// CommitContext sync the session to store with context
+func (sess *Session) CommitContext(ctx context.Context) (err error) {
+	if sess.committed {
 		return
 	}
-	opts := sess.opts
+	if !sess.modified {
+		if !sess.shouldRoll() {
+			return
+		}
+		var done bool
+		done, err = sess.roll(ctx)
+		if err != nil || done {
+			return
+		}
+	}
 	// not cookie value, create and set cookie
 	if sess.cookieValue == "" {
 		sess.RegenerateCookie()
 	}
-	marshal := json.Marshal
-	if opts.JSON != nil {
-		marshal = opts.JSON.Marshal
-	}
-	buf, err := marshal(sess.data)
+	err = sess.save(ctx)
 	if err != nil {
 		return
 	}
-	err = opts.Store.Set(sess.cookieValue, buf, opts.MaxAge)
+	store := sess.getStore()
+	hooks := sess.getHooks()
+	if !sess.stored {
+		emit(hooks.OnCreate, sess.cookieValue, sess.data, ReasonCommit)
+	}
+	emit(hooks.OnCommit, sess.cookieValue, sess.data, ReasonCommit)
+	sess.dirty = nil
+	sess.committed = true
+	sess.stored = true
+	userID := sess.GetUserID()
+	err = sess.addUser(userID)
 	if err != nil {
 		return
 	}
-	sess.committed = true
+	// remove the data of previous session id
+	if sess.prevCookieValue != "" {
+		prev := sess.prevCookieValue
+		sess.prevCookieValue = ""
+		err = store.DestroyContext(ctx, prev)
+		if err != nil {
+			return
+		}
+		err = sess.removeUser(userID, prev)
+	}
+	return
+}
+
+// Regenerate regenerate the session id and keep the data,
+// the data will be saved with the new id and the old one will be removed when commit.
+// It should be called after login to prevent session fixation.
+func (sess *Session) Regenerate() (err error) {
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	if sess.prevCookieValue == "" {
+		sess.prevCookieValue = sess.cookieValue
+	}
+	sess.committed = false
+	sess.modified = true
+	// the new session id has no stored version
+	sess.version = 0
+	sess.addSessionCookie(sess.genID())
+	emit(sess.getHooks().OnRegenerate, sess.cookieValue, sess.data, ReasonRegenerate)
 	return
 }
 
@@ -324,21 +578,62 @@ func (sess *Session) RegenerateCookie() {
 	if sess.committed {
 		return
 	}
+	// id := fn(opts.CookiePrefix)
+	id := sess.genID()
+	sess.addSessionCookie(id)
+}
+
+// genID generate a new session id
+func (sess *Session) genID() string {
 	opts := sess.opts
 	fn := opts.GenID
 	if fn == nil {
 		fn = generateID
+		if opts.IDSize > 0 || opts.IDEncoding != IDEncodingBase62 {
+			fn = NewIDGenerator(opts.IDSize, opts.IDEncoding)
+		}
 	}
-	// id := fn(opts.CookiePrefix)
-	id := fn()
-	sess.addSessionCookie(id)
+	return fn()
 }
 
 func (sess *Session) addSessionCookie(value string) {
 	sess.cookieValue = value
 	cookieName := sess.getCookieName()
-	cookie := sess.cookies.CreateCookie(cookieName, value)
-	sess.cookies.Set(cookie, sess.signed)
+	// the session has its own max age
+	maxAge := cast.ToInt(sess.data[MaxAge])
+	sess.transport.Set(cookieName, value, maxAge)
+}
+
+// SetMaxAge set the max age(seconds) for the session, it overrides the Options.MaxAge,
+// and will be applied to the store ttl and cookie. If seconds <= 0,
+// the Options.MaxAge will be used.
+func (sess *Session) SetMaxAge(seconds int) (err error) {
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	if seconds > 0 {
+		sess.data[MaxAge] = seconds
+	} else {
+		delete(sess.data, MaxAge)
+	}
+	sess.data[UpdatedAt] = time.Now().Format(time.RFC3339)
+	sess.markDirty(MaxAge, UpdatedAt)
+	if sess.cookieValue != "" {
+		sess.addSessionCookie(sess.cookieValue)
+	}
+	return
+}
+
+// GetMaxAge get the max age(seconds) of session
+func (sess *Session) GetMaxAge() int {
+	maxAge := cast.ToInt(sess.data[MaxAge])
+	if maxAge > 0 {
+		return maxAge
+	}
+	if sess.opts == nil {
+		return 0
+	}
+	return sess.opts.MaxAge
 }
 
 // GetData get the session's data
@@ -346,15 +641,9 @@ func (sess *Session) GetData() M {
 	return sess.data
 }
 
-var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
-
-// generateID gen id
-func generateID() string {
-	b := make([]rune, 24)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
-	}
-	return string(b)
+// GetID get the session's id, it's empty if the session isn't created
+func (sess *Session) GetID() string {
+	return sess.cookieValue
 }
 
 // New create a session instance
@@ -362,12 +651,23 @@ func New(rw cookies.ReadWriter, opts *Options) *Session {
 	if opts == nil || opts.Store == nil {
 		panic(errors.New("the options for session should not be nil"))
 	}
+	transport := NewCookieTransport(rw, opts.CookieOptions)
+	sess := NewWithTransport(transport, opts)
+	sess.signed = transport.signed
+	return sess
+}
+
+// NewWithTransport create a session instance with transport,
+// the session id will be read and written by the transport
+func NewWithTransport(transport Transport, opts *Options) *Session {
+	if opts == nil || opts.Store == nil {
+		panic(errors.New("the options for session should not be nil"))
+	}
+	if transport == nil {
+		panic(errors.New("the transport for session should not be nil"))
+	}
 	sess := &Session{}
 	sess.opts = opts
-	cookieOptions := opts.CookieOptions
-	sess.cookies = cookies.New(rw, cookieOptions)
-	if cookieOptions != nil && len(cookieOptions.Keys) != 0 {
-		sess.signed = true
-	}
+	sess.transport = transport
 	return sess
 }
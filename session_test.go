@@ -1,11 +1,13 @@
 package session
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/vicanso/cookies"
 
@@ -14,6 +16,32 @@ import (
 	"github.com/go-redis/redis"
 )
 
+// newHeaderSession create a session of the store whose id is read from
+// the request header, the store of options is replaced by the store
+func newHeaderSession(store Store, id string, opts Options) *Session {
+	r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+	r.Header.Set(HeaderSessionID, id)
+	w := httptest.NewRecorder()
+	opts.Store = store
+	return NewWithTransport(NewHeaderTransport(r, w, HeaderSessionID), &opts)
+}
+
+// newCookieSession save the data to store with ttl, then create a session
+// whose id is read from the request cookie, the store of options is replaced
+// by the store
+func newCookieSession(store Store, id string, data M, ttl int, opts *Options) (*Session, *httptest.ResponseRecorder) {
+	buf, _ := json.Marshal(data)
+	store.Set(id, buf, ttl)
+	r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+	r.AddCookie(&http.Cookie{
+		Name:  defaultCookieName,
+		Value: id,
+	})
+	w := httptest.NewRecorder()
+	opts.Store = store
+	return New(cookies.NewHTTPReadWriter(r, w), opts), w
+}
+
 func TestSession(t *testing.T) {
 	store := NewRedisStore(nil, &redis.Options{
 		Addr: "localhost:6379",
@@ -41,6 +69,26 @@ func TestSession(t *testing.T) {
 		}
 	})
 
+	t.Run("fetch session with canceled context", func(t *testing.T) {
+		cookie := &http.Cookie{
+			Name:  defaultCookieName,
+			Value: generateID(),
+		}
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		rw := cookies.NewHTTPReadWriter(r, w)
+		sess := New(rw, &Options{
+			Store: store,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := sess.FetchContext(ctx)
+		if err != context.Canceled {
+			t.Fatalf("fetch session with canceled context should return error")
+		}
+	})
+
 	t.Run("fetch session when cookie exists and not signed", func(t *testing.T) {
 		cookieValue := generateID()
 		cookie := &http.Cookie{
@@ -98,6 +146,72 @@ func TestSession(t *testing.T) {
 		}
 	})
 
+	t.Run("fetch session with invalid id", func(t *testing.T) {
+		cookieValue := "abc"
+		cookie := &http.Cookie{
+			Name:  defaultCookieName,
+			Value: cookieValue,
+		}
+		buf, _ := json.Marshal(map[string]interface{}{
+			"name": "tree.xie",
+		})
+		store.Set(cookieValue, buf, 60)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		rw := cookies.NewHTTPReadWriter(r, w)
+		sess := New(rw, &Options{
+			Store:      store,
+			ValidateID: NewIDValidator(0, IDEncodingBase62),
+		})
+		data, err := sess.Fetch()
+		if err != nil {
+			t.Fatalf("fetch session with invalid id fail, %v", err)
+		}
+		if len(data) != 1 || sess.cookieValue != "" {
+			t.Fatalf("invalid id should be rejected")
+		}
+	})
+
+	t.Run("fetch session with unknown id in strict mode", func(t *testing.T) {
+		cookieValue := generateID()
+		cookie := &http.Cookie{
+			Name:  defaultCookieName,
+			Value: cookieValue,
+		}
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		rw := cookies.NewHTTPReadWriter(r, w)
+		unknownID := ""
+		sess := New(rw, &Options{
+			Store:  store,
+			Strict: true,
+			OnUnknownID: func(id string) {
+				unknownID = id
+			},
+		})
+		_, err := sess.Fetch()
+		if err != nil {
+			t.Fatalf("fetch session fail, %v", err)
+		}
+		if unknownID != cookieValue {
+			t.Fatalf("unknown id should be reported")
+		}
+		sess.Set("name", "tree.xie")
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		if sess.cookieValue == "" || sess.cookieValue == cookieValue {
+			t.Fatalf("a new session id should be generated in strict mode")
+		}
+		buf, _ := store.Get(cookieValue)
+		if len(buf) != 0 {
+			t.Fatalf("the data should not be saved with unknown id")
+		}
+	})
+
 	t.Run("fetch session when cookie exists and signed incorrect", func(t *testing.T) {
 		cookieValue := generateID()
 		cookie := &http.Cookie{
@@ -336,6 +450,124 @@ func TestSession(t *testing.T) {
 		}
 	})
 
+	t.Run("regenerate", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		rw := cookies.NewHTTPReadWriter(r, w)
+		sess := New(rw, &Options{
+			Store:  ms,
+			MaxAge: 60,
+		})
+		err := sess.Regenerate()
+		if err != ErrNotFetched {
+			t.Fatalf("the session should fetch before regenerate")
+		}
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		oldID := sess.cookieValue
+
+		r = httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(&http.Cookie{
+			Name:  defaultCookieName,
+			Value: oldID,
+		})
+		w = httptest.NewRecorder()
+		rw = cookies.NewHTTPReadWriter(r, w)
+		sess = New(rw, &Options{
+			Store:  ms,
+			MaxAge: 60,
+		})
+		sess.Fetch()
+		err = sess.Regenerate()
+		if err != nil {
+			t.Fatalf("regenerate session fail, %v", err)
+		}
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit regenerated session fail, %v", err)
+		}
+		newID := sess.cookieValue
+		if newID == oldID {
+			t.Fatalf("the session id should be changed after regenerate")
+		}
+		if !strings.HasPrefix(w.HeaderMap["Set-Cookie"][0], defaultCookieName+"="+newID) {
+			t.Fatalf("the cookie should be rewritten after regenerate")
+		}
+		buf, _ := ms.Get(oldID)
+		if len(buf) != 0 {
+			t.Fatalf("the data of old id should be removed")
+		}
+		buf, _ = ms.Get(newID)
+		m := make(M)
+		json.Unmarshal(buf, &m)
+		if m["name"] != "tree.xie" {
+			t.Fatalf("the data should be moved to new id")
+		}
+
+		// regenerate after commit
+		err = sess.Regenerate()
+		if err != nil {
+			t.Fatalf("regenerate committed session fail, %v", err)
+		}
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit regenerated session fail, %v", err)
+		}
+		buf, _ = ms.Get(newID)
+		if len(buf) != 0 || sess.cookieValue == newID {
+			t.Fatalf("regenerate committed session fail")
+		}
+	})
+
+	t.Run("set max age", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		rw := cookies.NewHTTPReadWriter(r, w)
+		sess := New(rw, &Options{
+			Store:  ms,
+			MaxAge: 60,
+		})
+		err := sess.SetMaxAge(3600)
+		if err != ErrNotFetched {
+			t.Fatalf("the session should fetch before set max age")
+		}
+		sess.Fetch()
+		if sess.GetMaxAge() != 60 {
+			t.Fatalf("the max age should be options.MaxAge by default")
+		}
+		err = sess.SetMaxAge(3600)
+		if err != nil {
+			t.Fatalf("set max age fail, %v", err)
+		}
+		err = sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		if !strings.Contains(w.HeaderMap["Set-Cookie"][0], "Max-Age=3600") {
+			t.Fatalf("the cookie max age should be the session max age")
+		}
+		v, _ := ms.client.Get(sess.cookieValue)
+		info := v.(*MemoryStoreInfo)
+		if info.ExpiredAt < time.Now().Unix()+3000 {
+			t.Fatalf("the store ttl should be the session max age")
+		}
+		m := make(M)
+		json.Unmarshal(info.Data, &m)
+		sess = Mock(M{
+			"fetched": true,
+			"data":    m,
+		})
+		if sess.GetMaxAge() != 3600 {
+			t.Fatalf("the session max age should be persisted")
+		}
+	})
+
 	t.Run("session get(type) function", func(t *testing.T) {
 		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
 		w := httptest.NewRecorder()
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"context"
+)
+
+type (
+	// storeContextAdapter adapter for the store without context
+	storeContextAdapter struct {
+		store Store
+	}
+)
+
+// getStoreContext convert the store to context store,
+// if the store is not a context store, it will use an adapter
+func getStoreContext(store Store) StoreContext {
+	if sc, ok := store.(StoreContext); ok {
+		return sc
+	}
+	return &storeContextAdapter{
+		store: store,
+	}
+}
+
+// runContext run the function and return the error of context if it's done
+// before the function returns. The function isn't interrupted(e.g. the redis
+// command keeps waiting for its reply until the read timeout), but the caller
+// won't be blocked by it
+func runContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// the context can never be done
+	if ctx.Done() == nil {
+		return fn()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getContext get the session data by the function with context
+func getContext(ctx context.Context, key string, get func(string) ([]byte, error)) ([]byte, error) {
+	var data []byte
+	err := runContext(ctx, func() (err error) {
+		data, err = get(key)
+		return
+	})
+	// the data can't be read if the context is done, the function may be still running
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetContext get the session data, return error if the context is done
+func (sa *storeContextAdapter) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return getContext(ctx, key, sa.store.Get)
+}
+
+// SetContext set the session data, return error if the context is done
+func (sa *storeContextAdapter) SetContext(ctx context.Context, key string, data []byte, ttl int) error {
+	return runContext(ctx, func() error {
+		return sa.store.Set(key, data, ttl)
+	})
+}
+
+// DestroyContext remove the session data, return error if the context is done
+func (sa *storeContextAdapter) DestroyContext(ctx context.Context, key string) error {
+	return runContext(ctx, func() error {
+		return sa.store.Destroy(key)
+	})
+}
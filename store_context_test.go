@@ -0,0 +1,138 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+type plainStore struct {
+	data map[string][]byte
+}
+
+func (ps *plainStore) Get(key string) ([]byte, error) {
+	return ps.data[key], nil
+}
+
+func (ps *plainStore) Set(key string, data []byte, ttl int) error {
+	ps.data[key] = data
+	return nil
+}
+
+func (ps *plainStore) Destroy(key string) error {
+	delete(ps.data, key)
+	return nil
+}
+
+func TestStoreContext(t *testing.T) {
+	key := generateID()
+	data := []byte("tree.xie")
+
+	t.Run("context store", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		if getStoreContext(ms) != StoreContext(ms) {
+			t.Fatalf("context store should not be wrapped")
+		}
+	})
+
+	t.Run("store adapter", func(t *testing.T) {
+		sc := getStoreContext(&plainStore{
+			data: make(map[string][]byte),
+		})
+		ctx := context.Background()
+		err := sc.SetContext(ctx, key, data, 60)
+		if err != nil {
+			t.Fatalf("set data fail, %v", err)
+		}
+		buf, err := sc.GetContext(ctx, key)
+		if err != nil || !bytes.Equal(buf, data) {
+			t.Fatalf("get data fail, %v", err)
+		}
+		err = sc.DestroyContext(ctx, key)
+		if err != nil {
+			t.Fatalf("destroy data fail, %v", err)
+		}
+		buf, _ = sc.GetContext(ctx, key)
+		if len(buf) != 0 {
+			t.Fatalf("the data should be removed")
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		sc := getStoreContext(&plainStore{
+			data: make(map[string][]byte),
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := sc.GetContext(ctx, key); err != context.Canceled {
+			t.Fatalf("get with canceled context should return error")
+		}
+		if err := sc.SetContext(ctx, key, data, 60); err != context.Canceled {
+			t.Fatalf("set with canceled context should return error")
+		}
+		if err := sc.DestroyContext(ctx, key); err != context.Canceled {
+			t.Fatalf("destroy with canceled context should return error")
+		}
+	})
+
+	t.Run("redis without reply", func(t *testing.T) {
+		// the server reads the commands but never replies
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen fail, %v", err)
+		}
+		defer ln.Close()
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					io.Copy(ioutil.Discard, conn)
+					conn.Close()
+				}()
+			}
+		}()
+		opts := &redis.Options{
+			Addr:        ln.Addr().String(),
+			ReadTimeout: 3 * time.Second,
+		}
+		stores := map[string]StoreContext{
+			"redis":      NewRedisStore(nil, opts),
+			"redis hash": NewRedisHashStore(nil, opts),
+		}
+		for name, store := range stores {
+			fns := map[string]func(context.Context) error{
+				"get": func(ctx context.Context) error {
+					_, err := store.GetContext(ctx, key)
+					return err
+				},
+				"set": func(ctx context.Context) error {
+					return store.SetContext(ctx, key, []byte(`{"name":"tree.xie"}`), 60)
+				},
+				"destroy": func(ctx context.Context) error {
+					return store.DestroyContext(ctx, key)
+				},
+			}
+			for op, fn := range fns {
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				start := time.Now()
+				err := fn(ctx)
+				cancel()
+				if err != context.DeadlineExceeded {
+					t.Fatalf("%s of %s store should return deadline exceeded error, %v", op, name, err)
+				}
+				if time.Since(start) > time.Second {
+					t.Fatalf("%s of %s store should not be blocked after the context is done", op, name)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"time"
+)
+
+// getTime get the time value(RFC3339) of session data
+func getTime(m M, key string) (t time.Time, ok bool) {
+	v, _ := m[key].(string)
+	if v == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+// getLastActiveTime get the last active time of session data
+func getLastActiveTime(m M) (time.Time, bool) {
+	t, ok := getTime(m, UpdatedAt)
+	if ok {
+		return t, ok
+	}
+	return getTime(m, CreatedAt)
+}
+
+// getRemaining get the remaining duration from the time
+func getRemaining(t time.Time, seconds int) time.Duration {
+	d := t.Add(time.Duration(seconds) * time.Second).Sub(time.Now())
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// isTimeout check the session data is idle timeout or absolute timeout,
+// the reason is ReasonAbsoluteTimeout or ReasonIdleTimeout
+func (sess *Session) isTimeout(m M) (reason string, timeout bool) {
+	opts := sess.opts
+	if opts.AbsoluteTimeout > 0 {
+		createdAt, ok := getTime(m, CreatedAt)
+		if ok && getRemaining(createdAt, opts.AbsoluteTimeout) == 0 {
+			return ReasonAbsoluteTimeout, true
+		}
+	}
+	if opts.IdleTimeout > 0 {
+		activeAt, ok := getLastActiveTime(m)
+		if ok && getRemaining(activeAt, opts.IdleTimeout) == 0 {
+			return ReasonIdleTimeout, true
+		}
+	}
+	return
+}
+
+// IdleTimeRemaining get the remaining time before the session is idle timeout,
+// it will return 0 if the session isn't fetched or the idle timeout isn't set
+func (sess *Session) IdleTimeRemaining() time.Duration {
+	if !sess.fetched || sess.opts == nil || sess.opts.IdleTimeout <= 0 {
+		return 0
+	}
+	activeAt, ok := getLastActiveTime(sess.data)
+	if !ok {
+		return 0
+	}
+	return getRemaining(activeAt, sess.opts.IdleTimeout)
+}
+
+// AbsoluteTimeRemaining get the remaining time before the session is absolute timeout,
+// it will return 0 if the session isn't fetched or the absolute timeout isn't set
+func (sess *Session) AbsoluteTimeRemaining() time.Duration {
+	if !sess.fetched || sess.opts == nil || sess.opts.AbsoluteTimeout <= 0 {
+		return 0
+	}
+	createdAt, ok := getTime(sess.data, CreatedAt)
+	if !ok {
+		return 0
+	}
+	return getRemaining(createdAt, sess.opts.AbsoluteTimeout)
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	ms, _ := NewMemoryStore(10)
+	hourAgo := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	now := time.Now().Format(time.RFC3339)
+
+	t.Run("idle timeout", func(t *testing.T) {
+		id := generateID()
+		sess, _ := newCookieSession(ms, id, M{
+			"name":    "tree.xie",
+			CreatedAt: hourAgo,
+			UpdatedAt: hourAgo,
+		}, 60, &Options{
+			IdleTimeout: 60,
+		})
+		data, err := sess.Fetch()
+		if err != nil {
+			t.Fatalf("fetch session fail, %v", err)
+		}
+		if data["name"] != nil || sess.cookieValue != "" {
+			t.Fatalf("idle timeout session should be reset")
+		}
+		buf, _ := ms.Get(id)
+		if len(buf) != 0 {
+			t.Fatalf("idle timeout session should be destroyed")
+		}
+	})
+
+	t.Run("read only requests renew idle timeout", func(t *testing.T) {
+		id := generateID()
+		sess, _ := newCookieSession(ms, id, M{
+			"name":    "tree.xie",
+			CreatedAt: hourAgo,
+			UpdatedAt: time.Now().Add(-50 * time.Second).Format(time.RFC3339),
+		}, 60, &Options{
+			IdleTimeout: 60,
+			MaxAge:      60,
+		})
+		sess.Fetch()
+		// only read the session
+		if sess.GetString("name") != "tree.xie" {
+			t.Fatalf("the session should not be timeout")
+		}
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		buf, _ := ms.Get(id)
+		m := make(M)
+		json.Unmarshal(buf, &m)
+		activeAt, _ := getLastActiveTime(m)
+		if time.Since(activeAt) > 5*time.Second {
+			t.Fatalf("the fetch should renew the idle timeout")
+		}
+	})
+
+	t.Run("absolute timeout", func(t *testing.T) {
+		id := generateID()
+		sess, _ := newCookieSession(ms, id, M{
+			"name":    "tree.xie",
+			CreatedAt: hourAgo,
+			UpdatedAt: now,
+		}, 60, &Options{
+			IdleTimeout:     60,
+			AbsoluteTimeout: 1800,
+		})
+		data, _ := sess.Fetch()
+		if data["name"] != nil {
+			t.Fatalf("absolute timeout session should be reset")
+		}
+	})
+
+	t.Run("remaining", func(t *testing.T) {
+		id := generateID()
+		sess, _ := newCookieSession(ms, id, M{
+			"name":    "tree.xie",
+			CreatedAt: hourAgo,
+			UpdatedAt: now,
+		}, 60, &Options{
+			IdleTimeout:     60,
+			AbsoluteTimeout: 7200,
+		})
+		if sess.IdleTimeRemaining() != 0 || sess.AbsoluteTimeRemaining() != 0 {
+			t.Fatalf("the remaining time should be 0 before fetch")
+		}
+		data, _ := sess.Fetch()
+		if data["name"] != "tree.xie" {
+			t.Fatalf("session should not be timeout")
+		}
+		idle := sess.IdleTimeRemaining()
+		if idle <= 50*time.Second || idle > 60*time.Second {
+			t.Fatalf("get idle remaining time fail, %v", idle)
+		}
+		absolute := sess.AbsoluteTimeRemaining()
+		if absolute <= 3590*time.Second || absolute > 3600*time.Second {
+			t.Fatalf("get absolute remaining time fail, %v", absolute)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vicanso/cookies"
+)
+
+const (
+	// HeaderAuthorization authorization header
+	HeaderAuthorization = "Authorization"
+	// HeaderSessionID session id header
+	HeaderSessionID = "X-Session-Id"
+	// SchemeBearer bearer scheme of authorization
+	SchemeBearer = "Bearer"
+)
+
+type (
+	// CookieTransport cookie transport for session id
+	CookieTransport struct {
+		cookies *cookies.Cookies
+		signed  bool
+	}
+	// HeaderTransport header transport for session id
+	HeaderTransport struct {
+		req *http.Request
+		res http.ResponseWriter
+		// the request header to get session id
+		Header string
+		// the scheme of request header, e.g. Bearer
+		Scheme string
+		// the response header to set session id, default is the same as Header
+		ResponseHeader string
+	}
+	// QueryTransport query parameter transport for session id,
+	// it can only read the session id
+	QueryTransport struct {
+		req *http.Request
+		// the query parameter name, default is the session key
+		Param string
+	}
+	// ChainTransport chain transport, it will try to get the session id
+	// from transports in order
+	ChainTransport struct {
+		transports []Transport
+		// the transport which the session id is read from
+		matched Transport
+	}
+)
+
+// NewCookieTransport create a cookie transport,
+// the cookie will be signed if the keys of options is not empty
+func NewCookieTransport(rw cookies.ReadWriter, opts *cookies.Options) *CookieTransport {
+	ct := &CookieTransport{
+		cookies: cookies.New(rw, opts),
+	}
+	if opts != nil && len(opts.Keys) != 0 {
+		ct.signed = true
+	}
+	return ct
+}
+
+// Get get the session id from cookie
+func (ct *CookieTransport) Get(name string) string {
+	return ct.cookies.Get(name, ct.signed)
+}
+
+// Set set the session id to cookie
+func (ct *CookieTransport) Set(name, value string, maxAge int) {
+	cookie := ct.cookies.CreateCookie(name, value)
+	if maxAge > 0 {
+		cookie.MaxAge = maxAge
+		cookie.Expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+	}
+	ct.cookies.Set(cookie, ct.signed)
+}
+
+// NewHeaderTransport create a header transport,
+// the session id will be read from request header and written to response header
+func NewHeaderTransport(req *http.Request, res http.ResponseWriter, header string) *HeaderTransport {
+	return &HeaderTransport{
+		req:    req,
+		res:    res,
+		Header: header,
+	}
+}
+
+// NewBearerTransport create a bearer transport, the session id will be read
+// from "Authorization: Bearer <id>" and written to response header "X-Session-Id"
+func NewBearerTransport(req *http.Request, res http.ResponseWriter) *HeaderTransport {
+	return &HeaderTransport{
+		req:            req,
+		res:            res,
+		Header:         HeaderAuthorization,
+		Scheme:         SchemeBearer,
+		ResponseHeader: HeaderSessionID,
+	}
+}
+
+// Get get the session id from request header
+func (ht *HeaderTransport) Get(name string) string {
+	value := strings.TrimSpace(ht.req.Header.Get(ht.Header))
+	if ht.Scheme == "" || value == "" {
+		return value
+	}
+	prefix := ht.Scheme + " "
+	if len(value) <= len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(value[len(prefix):])
+}
+
+// Set set the session id to response header
+func (ht *HeaderTransport) Set(name, value string, maxAge int) {
+	header := ht.ResponseHeader
+	if header == "" {
+		header = ht.Header
+	}
+	ht.res.Header().Set(header, value)
+}
+
+// NewQueryTransport create a query transport
+func NewQueryTransport(req *http.Request, param string) *QueryTransport {
+	return &QueryTransport{
+		req:   req,
+		Param: param,
+	}
+}
+
+// Get get the session id from query
+func (qt *QueryTransport) Get(name string) string {
+	param := qt.Param
+	if param == "" {
+		param = name
+	}
+	return qt.req.URL.Query().Get(param)
+}
+
+// Set the session id can't be written to query, so it does nothing
+func (qt *QueryTransport) Set(name, value string, maxAge int) {
+}
+
+// ReadOnly the query transport can't write the session id
+func (qt *QueryTransport) ReadOnly() bool {
+	return true
+}
+
+// isReadOnly check the transport can't write the session id
+func isReadOnly(t Transport) bool {
+	rt, ok := t.(interface {
+		ReadOnly() bool
+	})
+	return ok && rt.ReadOnly()
+}
+
+// NewChainTransport create a chain transport
+func NewChainTransport(transports ...Transport) *ChainTransport {
+	return &ChainTransport{
+		transports: transports,
+	}
+}
+
+// Get get the session id from the transports in order, return the first not empty one
+func (ct *ChainTransport) Get(name string) string {
+	for _, t := range ct.transports {
+		value := t.Get(name)
+		if value != "" {
+			ct.matched = t
+			return value
+		}
+	}
+	return ""
+}
+
+// Set set the session id to the transport which the session id is read from,
+// if the session id isn't read from any transport or the matched transport
+// is read only(e.g. QueryTransport), it will be set to all transports
+func (ct *ChainTransport) Set(name, value string, maxAge int) {
+	if ct.matched != nil && !isReadOnly(ct.matched) {
+		ct.matched.Set(name, value, maxAge)
+		return
+	}
+	for _, t := range ct.transports {
+		t.Set(name, value, maxAge)
+	}
+}
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vicanso/cookies"
+)
+
+func TestTransport(t *testing.T) {
+	id := generateID()
+
+	t.Run("cookie transport", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.AddCookie(&http.Cookie{
+			Name:  defaultCookieName,
+			Value: id,
+		})
+		w := httptest.NewRecorder()
+		ct := NewCookieTransport(cookies.NewHTTPReadWriter(r, w), nil)
+		if ct.Get(defaultCookieName) != id {
+			t.Fatalf("get session id from cookie fail")
+		}
+		ct.Set(defaultCookieName, id, 60)
+		values := w.HeaderMap["Set-Cookie"]
+		if len(values) != 1 || !strings.Contains(values[0], "Max-Age=60") {
+			t.Fatalf("set session id to cookie fail")
+		}
+	})
+
+	t.Run("header transport", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.Header.Set(HeaderSessionID, id)
+		w := httptest.NewRecorder()
+		ht := NewHeaderTransport(r, w, HeaderSessionID)
+		if ht.Get(defaultCookieName) != id {
+			t.Fatalf("get session id from header fail")
+		}
+		ht.Set(defaultCookieName, "a", 0)
+		if w.Header().Get(HeaderSessionID) != "a" {
+			t.Fatalf("set session id to header fail")
+		}
+	})
+
+	t.Run("bearer transport", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		r.Header.Set(HeaderAuthorization, "bearer "+id)
+		w := httptest.NewRecorder()
+		bt := NewBearerTransport(r, w)
+		if bt.Get(defaultCookieName) != id {
+			t.Fatalf("get session id from bearer token fail")
+		}
+		r.Header.Set(HeaderAuthorization, "Basic "+id)
+		if bt.Get(defaultCookieName) != "" {
+			t.Fatalf("other scheme should be ignored")
+		}
+		bt.Set(defaultCookieName, "a", 0)
+		if w.Header().Get(HeaderSessionID) != "a" {
+			t.Fatalf("set session id to response header fail")
+		}
+	})
+
+	t.Run("query transport", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me?sess="+id, nil)
+		qt := NewQueryTransport(r, "")
+		if qt.Get(defaultCookieName) != id {
+			t.Fatalf("get session id from query fail")
+		}
+		qt.Set(defaultCookieName, "a", 0)
+	})
+
+	t.Run("chain transport", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		ct := NewChainTransport(
+			NewCookieTransport(cookies.NewHTTPReadWriter(r, w), nil),
+			NewBearerTransport(r, w),
+		)
+		if ct.Get(defaultCookieName) != "" {
+			t.Fatalf("session id should be empty")
+		}
+		ct.Set(defaultCookieName, "a", 0)
+		if len(w.HeaderMap["Set-Cookie"]) != 1 || w.Header().Get(HeaderSessionID) != "a" {
+			t.Fatalf("session id should be set to all transports")
+		}
+
+		r.Header.Set(HeaderAuthorization, "Bearer "+id)
+		w = httptest.NewRecorder()
+		ct = NewChainTransport(
+			NewCookieTransport(cookies.NewHTTPReadWriter(r, w), nil),
+			NewBearerTransport(r, w),
+		)
+		if ct.Get(defaultCookieName) != id {
+			t.Fatalf("get session id from chain transport fail")
+		}
+		ct.Set(defaultCookieName, "b", 0)
+		if len(w.HeaderMap["Set-Cookie"]) != 0 || w.Header().Get(HeaderSessionID) != "b" {
+			t.Fatalf("session id should be set to the matched transport")
+		}
+
+		// the matched transport is read only
+		r = httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me?sess="+id, nil)
+		w = httptest.NewRecorder()
+		ct = NewChainTransport(
+			NewQueryTransport(r, ""),
+			NewCookieTransport(cookies.NewHTTPReadWriter(r, w), nil),
+			NewBearerTransport(r, w),
+		)
+		if ct.Get(defaultCookieName) != id {
+			t.Fatalf("get session id from query transport fail")
+		}
+		ct.Set(defaultCookieName, "c", 0)
+		if len(w.HeaderMap["Set-Cookie"]) != 1 || w.Header().Get(HeaderSessionID) != "c" {
+			t.Fatalf("session id should be set to all transports if the matched one is read only")
+		}
+	})
+
+	t.Run("session with transport", func(t *testing.T) {
+		ms, _ := NewMemoryStore(10)
+		r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+		w := httptest.NewRecorder()
+		sess := NewWithTransport(NewBearerTransport(r, w), &Options{
+			Store: ms,
+		})
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		newID := w.Header().Get(HeaderSessionID)
+		if newID == "" {
+			t.Fatalf("session id should be set to header")
+		}
+
+		r.Header.Set(HeaderAuthorization, "Bearer "+newID)
+		sess = NewWithTransport(NewBearerTransport(r, httptest.NewRecorder()), &Options{
+			Store: ms,
+		})
+		sess.Fetch()
+		if sess.GetString("name") != "tree.xie" {
+			t.Fatalf("fetch session by bearer token fail")
+		}
+	})
+}
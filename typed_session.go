@@ -0,0 +1,34 @@
+//go:build go1.18
+// +build go1.18
+
+package session
+
+type (
+	// TypedSession typed session, the session data is bound to the struct T,
+	// and it's saved as the same map format of session
+	TypedSession[T any] struct {
+		*Session
+	}
+)
+
+// NewTypedSession create a typed session
+func NewTypedSession[T any](sess *Session) *TypedSession[T] {
+	return &TypedSession[T]{
+		Session: sess,
+	}
+}
+
+// Load fetch the session and bind the data to T
+func (ts *TypedSession[T]) Load() (v T, err error) {
+	_, err = ts.Fetch()
+	if err != nil {
+		return
+	}
+	err = ts.Bind(&v)
+	return
+}
+
+// Save save the T to session data
+func (ts *TypedSession[T]) Save(v T) error {
+	return ts.Session.Save(v)
+}
@@ -0,0 +1,50 @@
+//go:build go1.18
+// +build go1.18
+
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vicanso/cookies"
+)
+
+func TestTypedSession(t *testing.T) {
+	ms, _ := NewMemoryStore(10)
+	r := httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+	w := httptest.NewRecorder()
+	ts := NewTypedSession[bindUser](New(cookies.NewHTTPReadWriter(r, w), &Options{
+		Store: ms,
+	}))
+	u, err := ts.Load()
+	if err != nil {
+		t.Fatalf("load typed session fail, %v", err)
+	}
+	if u.Account != "" {
+		t.Fatalf("new session should be empty")
+	}
+	u.Account = "tree.xie"
+	err = ts.Save(u)
+	if err != nil {
+		t.Fatalf("save typed session fail, %v", err)
+	}
+	err = ts.Commit()
+	if err != nil {
+		t.Fatalf("commit typed session fail, %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "http://aslant.site/api/users/me", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	sess := New(cookies.NewHTTPReadWriter(r, httptest.NewRecorder()), &Options{
+		Store: ms,
+	})
+	ts = NewTypedSession[bindUser](sess)
+	u, _ = ts.Load()
+	if u.Account != "tree.xie" || sess.GetString("account") != "tree.xie" {
+		t.Fatalf("the typed session should be saved as map")
+	}
+}
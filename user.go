@@ -0,0 +1,84 @@
+package session
+
+import (
+	"github.com/spf13/cast"
+)
+
+// getUserIndex get the user index of store, it will return ErrNotUserIndex
+// if the store isn't an UserIndex(e.g. the wrapper stores)
+func (sess *Session) getUserIndex() (index UserIndex, err error) {
+	ok := false
+	if sess.opts != nil {
+		index, ok = sess.opts.Store.(UserIndex)
+	}
+	if !ok {
+		err = ErrNotUserIndex
+	}
+	return
+}
+
+// addUser add the session to the index of user
+func (sess *Session) addUser(userID string) error {
+	if userID == "" || sess.cookieValue == "" {
+		return nil
+	}
+	index, err := sess.getUserIndex()
+	if err != nil {
+		return err
+	}
+	return index.AddUserSession(userID, sess.cookieValue, sess.GetMaxAge())
+}
+
+// removeUser remove the session from the index of user
+func (sess *Session) removeUser(userID, key string) error {
+	if userID == "" || key == "" {
+		return nil
+	}
+	index, err := sess.getUserIndex()
+	if err != nil {
+		return err
+	}
+	return index.RemoveUserSession(userID, key)
+}
+
+// BindUser bind the session to the user, the session will be added to
+// the index of user when commit, the store should be an UserIndex,
+// otherwise ErrNotUserIndex will be returned.
+// If the user id is empty, the session will be unbound. If the sessions of user
+// exceed the MaxUserSessions, the oldest session will be evicted or
+// UserSessionLimitError will be returned by the UserSessionPolicy.
+func (sess *Session) BindUser(userID string) (err error) {
+	if !sess.fetched {
+		return ErrNotFetched
+	}
+	prev := sess.GetUserID()
+	if prev == userID {
+		return
+	}
+	index, err := sess.getUserIndex()
+	if err != nil {
+		return
+	}
+	if userID != "" && sess.opts.MaxUserSessions > 0 {
+		err = sess.limitUser(index, userID)
+		if err != nil {
+			return
+		}
+	}
+	// the session is bound to other user before
+	if sess.stored {
+		err = sess.removeUser(prev, sess.cookieValue)
+		if err != nil {
+			return
+		}
+	}
+	if userID == "" {
+		return sess.Set(UserID, nil)
+	}
+	return sess.Set(UserID, userID)
+}
+
+// GetUserID get the user id which the session is bound to
+func (sess *Session) GetUserID() string {
+	return cast.ToString(sess.data[UserID])
+}
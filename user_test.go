@@ -0,0 +1,128 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-redis/redis"
+)
+
+func TestUser(t *testing.T) {
+	opts := Options{
+		MaxAge: 60,
+	}
+	ms, _ := NewMemoryStore(10)
+	stores := map[string]Store{
+		"memory": ms,
+		"redis": NewRedisStore(nil, &redis.Options{
+			Addr: "localhost:6379",
+		}),
+		"redis hash": NewRedisHashStore(nil, &redis.Options{
+			Addr: "localhost:6379",
+		}),
+	}
+
+	t.Run("not fetched", func(t *testing.T) {
+		sess := newHeaderSession(ms, "", opts)
+		err := sess.BindUser("tree.xie")
+		if err != ErrNotFetched {
+			t.Fatalf("should return not fetched error")
+		}
+	})
+
+	t.Run("not user index", func(t *testing.T) {
+		store := NewCompressedStore(ms, 0, 0)
+		sess := newHeaderSession(store, "", Options{
+			MaxUserSessions: 1,
+		})
+		sess.Fetch()
+		err := sess.BindUser("tree.xie")
+		if err != ErrNotUserIndex {
+			t.Fatalf("bind user of not user index store should return error")
+		}
+		if sess.GetUserID() != "" {
+			t.Fatalf("the session should not be bound to user")
+		}
+
+		// the user id is set to session data directly
+		sess.Set(UserID, "tree.xie")
+		err = sess.Commit()
+		if err != ErrNotUserIndex {
+			t.Fatalf("commit session bound to user of not user index store should return error")
+		}
+	})
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			index := store.(UserIndex)
+			userID := generateID()
+			sess := newHeaderSession(store, "", opts)
+			sess.Fetch()
+			err := sess.BindUser(userID)
+			if err != nil {
+				t.Fatalf("bind user fail, %v", err)
+			}
+			if sess.GetUserID() != userID {
+				t.Fatalf("get user id fail")
+			}
+			err = sess.Commit()
+			if err != nil {
+				t.Fatalf("commit session fail, %v", err)
+			}
+			id := sess.GetID()
+			keys, err := index.UserSessions(userID)
+			if err != nil || !reflect.DeepEqual(keys, []string{id}) {
+				t.Fatalf("the session should be added to the index of user, %v", err)
+			}
+
+			// regenerate
+			sess = newHeaderSession(store, id, opts)
+			sess.Fetch()
+			sess.Regenerate()
+			err = sess.Commit()
+			if err != nil {
+				t.Fatalf("commit regenerated session fail, %v", err)
+			}
+			id = sess.cookieValue
+			keys, _ = index.UserSessions(userID)
+			if !reflect.DeepEqual(keys, []string{id}) {
+				t.Fatalf("the previous session should be removed from the index of user")
+			}
+
+			// unbind
+			sess = newHeaderSession(store, id, opts)
+			sess.Fetch()
+			sess.BindUser("")
+			sess.Commit()
+			keys, _ = index.UserSessions(userID)
+			if len(keys) != 0 {
+				t.Fatalf("the unbound session should be removed from the index of user")
+			}
+
+			// destroy
+			sess = newHeaderSession(store, id, opts)
+			sess.Fetch()
+			sess.BindUser(userID)
+			sess.Commit()
+			err = sess.Destroy()
+			if err != nil {
+				t.Fatalf("destroy session fail, %v", err)
+			}
+			keys, _ = index.UserSessions(userID)
+			if len(keys) != 0 {
+				t.Fatalf("the destroyed session should be removed from the index of user")
+			}
+
+			// expired session
+			sess = newHeaderSession(store, "", opts)
+			sess.Fetch()
+			sess.BindUser(userID)
+			sess.Commit()
+			store.Destroy(sess.cookieValue)
+			keys, _ = index.UserSessions(userID)
+			if len(keys) != 0 {
+				t.Fatalf("the expired session should be pruned from the index of user")
+			}
+		})
+	}
+}
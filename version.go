@@ -0,0 +1,105 @@
+package session
+
+import (
+	"context"
+)
+
+const (
+	// the max retry times of commit when conflicts
+	maxConflictRetries = 3
+)
+
+// getCASStore get the cas store if the session is versioned, it will
+// return ErrNotCASStore if the store of versioned session isn't a CASStore
+func (sess *Session) getCASStore() (store CASStore, err error) {
+	if !sess.opts.Versioned {
+		return
+	}
+	store, ok := sess.opts.Store.(CASStore)
+	if !ok {
+		err = ErrNotCASStore
+	}
+	return
+}
+
+// load get the session data from store, the version of data will be saved
+// if the session is versioned
+func (sess *Session) load(ctx context.Context, id string) (buf []byte, err error) {
+	store, err := sess.getCASStore()
+	if err != nil {
+		return
+	}
+	if store == nil {
+		return sess.getStore().GetContext(ctx, id)
+	}
+	err = ctx.Err()
+	if err != nil {
+		return
+	}
+	buf, sess.version, err = store.GetVersion(id)
+	return
+}
+
+// save save the session data to store, if the session is versioned,
+// it will be saved only if the stored version isn't moved
+func (sess *Session) save(ctx context.Context) (err error) {
+	if store, ok := sess.getPartialStore(); ok {
+		return sess.savePartial(ctx, store)
+	}
+	store, err := sess.getCASStore()
+	if err != nil {
+		return
+	}
+	buf, err := sess.marshal(sess.data)
+	if err != nil {
+		return
+	}
+	id := sess.cookieValue
+	if store == nil {
+		return sess.getStore().SetContext(ctx, id, buf, sess.GetMaxAge())
+	}
+	for i := 0; ; i++ {
+		err = ctx.Err()
+		if err != nil {
+			return
+		}
+		err = store.SetVersion(id, buf, sess.GetMaxAge(), sess.version)
+		if err == nil {
+			sess.version++
+			return
+		}
+		if err != ErrConflict || sess.opts.Merge == nil || i >= maxConflictRetries {
+			return
+		}
+		// merge the latest data and retry
+		var latest []byte
+		var version int64
+		latest, version, err = store.GetVersion(id)
+		if err != nil {
+			return
+		}
+		stored := make(M)
+		if len(latest) != 0 {
+			err = sess.unmarshal(latest, &stored)
+			if err != nil {
+				return
+			}
+		}
+		var merged M
+		merged, err = sess.opts.Merge(stored, sess.data)
+		if err != nil {
+			return
+		}
+		sess.data = merged
+		sess.version = version
+		buf, err = sess.marshal(sess.data)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Version get the version of session data, it's only available for versioned session
+func (sess *Session) Version() int64 {
+	return sess.version
+}
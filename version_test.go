@@ -0,0 +1,140 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-redis/redis"
+)
+
+func TestVersion(t *testing.T) {
+	versioned := Options{
+		Versioned: true,
+	}
+	ms, _ := NewMemoryStore(10)
+	rs := NewRedisStore(nil, &redis.Options{
+		Addr: "localhost:6379",
+	})
+	stores := map[string]Store{
+		"memory": ms,
+		"redis":  rs,
+	}
+
+	for name, store := range stores {
+		t.Run(name+" conflict", func(t *testing.T) {
+			id := generateID()
+			buf, _ := json.Marshal(M{
+				"count": 1,
+			})
+			store.Set(id, buf, 60)
+
+			sess1 := newHeaderSession(store, id, versioned)
+			sess2 := newHeaderSession(store, id, versioned)
+			sess1.Fetch()
+			sess2.Fetch()
+			if sess1.Version() != sess2.Version() {
+				t.Fatalf("the version of session should be fetched")
+			}
+			version := sess1.Version()
+			sess1.Set("count", 2)
+			err := sess1.Commit()
+			if err != nil {
+				t.Fatalf("commit versioned session fail, %v", err)
+			}
+			if sess1.Version() != version+1 {
+				t.Fatalf("the version should be increased after commit")
+			}
+			sess2.Set("count", 3)
+			err = sess2.Commit()
+			if err != ErrConflict {
+				t.Fatalf("commit stale session should return conflict error")
+			}
+			data, _ := store.Get(id)
+			m := make(M)
+			json.Unmarshal(data, &m)
+			if m["count"].(float64) != 2 {
+				t.Fatalf("stale session should not overwrite the data")
+			}
+		})
+
+		t.Run(name+" merge", func(t *testing.T) {
+			id := generateID()
+			buf, _ := json.Marshal(M{
+				"count": 1,
+			})
+			store.Set(id, buf, 60)
+			merge := func(stored, local M) (M, error) {
+				stored["name"] = local["name"]
+				return stored, nil
+			}
+			sess1 := newHeaderSession(store, id, versioned)
+			sess2 := newHeaderSession(store, id, Options{
+				Versioned: true,
+				Merge:     merge,
+			})
+			sess1.Fetch()
+			sess2.Fetch()
+			sess1.Set("count", 2)
+			sess1.Commit()
+			sess2.Set("name", "tree.xie")
+			err := sess2.Commit()
+			if err != nil {
+				t.Fatalf("commit session with merge fail, %v", err)
+			}
+			data, _ := store.Get(id)
+			m := make(M)
+			json.Unmarshal(data, &m)
+			if m["count"].(float64) != 2 || m["name"] != "tree.xie" {
+				t.Fatalf("the data should be merged")
+			}
+			if sess2.Version() != sess1.Version()+1 {
+				t.Fatalf("the version should be the latest after merge")
+			}
+		})
+
+		t.Run(name+" new session", func(t *testing.T) {
+			sess := newHeaderSession(store, "", versioned)
+			sess.Fetch()
+			sess.Set("name", "tree.xie")
+			err := sess.Commit()
+			if err != nil {
+				t.Fatalf("commit new versioned session fail, %v", err)
+			}
+			if sess.Version() != 1 {
+				t.Fatalf("the version of new session should be 1")
+			}
+		})
+	}
+
+	t.Run("not cas store", func(t *testing.T) {
+		store := NewCompressedStore(ms, 0, 0)
+		sess := newHeaderSession(store, generateID(), versioned)
+		_, err := sess.Fetch()
+		if err != ErrNotCASStore {
+			t.Fatalf("fetch versioned session of not cas store should return error")
+		}
+		sess = newHeaderSession(store, "", versioned)
+		sess.opts.Versioned = false
+		sess.Fetch()
+		sess.opts.Versioned = true
+		sess.Set("name", "tree.xie")
+		err = sess.Commit()
+		if err != ErrNotCASStore {
+			t.Fatalf("commit versioned session of not cas store should return error")
+		}
+	})
+
+	t.Run("not versioned", func(t *testing.T) {
+		id := generateID()
+		sess := newHeaderSession(ms, id, Options{})
+		sess.Fetch()
+		sess.Set("name", "tree.xie")
+		err := sess.Commit()
+		if err != nil {
+			t.Fatalf("commit session fail, %v", err)
+		}
+		if sess.Version() != 0 {
+			t.Fatalf("not versioned session should not have version")
+		}
+	})
+}